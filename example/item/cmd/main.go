@@ -1,20 +1,17 @@
 package main
 
 import (
-	"context"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	itemsV1 "github.com/ovargas/wizapp/example/api/items/v1"
 	_ "github.com/ovargas/wizapp/example/item/dftapp"
-	"github.com/ovargas/wizapp/example/item/internal/items/service"
+	"github.com/ovargas/wizapp/example/item/internal/items"
+	_ "github.com/ovargas/wizapp/sdk/admin_server"
 	"github.com/ovargas/wizapp/sdk/app"
-	"github.com/ovargas/wizapp/sdk/grpc_server"
-	"github.com/ovargas/wizapp/sdk/grpc_server/grpc_gateway_server"
+	"github.com/ovargas/wizapp/sdk/datasource"
 	"github.com/ovargas/wizapp/sdk/logger"
 	_ "github.com/ovargas/wizapp/sdk/sql_component"
 	"github.com/ovargas/wizapp/sdk/temporal_server"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
 	"os"
 )
@@ -24,24 +21,41 @@ var log = logger.Log()
 func main() {
 	app.Usage = "Item demo application"
 
+	app.RegisterModule(items.NewModule())
+	datasource.InstallHealthChecks(app.Default())
+
+	app.RegisterCommand(&app.Command{
+		Name:  "count-items",
+		Usage: "Print the number of items of the default datasource",
+	}, countItems)
+
 	if err := app.Run(os.Args, setup); err != nil {
-		log.Fatalf("unable to start application: %v", err)
+		log.Errorf("unable to start application: %v", err)
+		os.Exit(app.ExitCode(err))
 	}
 }
 
-func setup(config *app.ApplicationConfig) error {
-
-	grpclog.SetLogger(logger.Log())
+func countItems(ctx *app.CommandContext) error {
+	ds, err := datasource.FromCommand(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := ds.GetDefaultConnection()
+	if err != nil {
+		return err
+	}
 
-	itemService := service.New()
+	var count int
+	if err := db.GetContext(ctx.Context.Context, &count, "select count(*) from item"); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ctx.App.Writer, "%d items\n", count)
+	return err
+}
 
-	grpc_server.RegisterServerService(func(srv *grpc.Server) {
-		itemsV1.RegisterItemServiceServer(srv, itemService)
-	})
+func setup(config *app.ApplicationConfig) error {
 
-	grpc_gateway_server.RegisterServiceHandlers(func(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
-		return itemsV1.RegisterItemServiceHandler(context.Background(), mux, conn)
-	})
+	grpclog.SetLogger(logger.Log())
 
 	temporal_server.WorkerInterceptors()
 
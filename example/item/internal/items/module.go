@@ -0,0 +1,33 @@
+package items
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	itemsV1 "github.com/ovargas/wizapp/example/api/items/v1"
+	"github.com/ovargas/wizapp/example/item/internal/items/service"
+	"google.golang.org/grpc"
+)
+
+// ModuleName is the name of the items module, it is disabled with modules.items.enabled: false.
+const ModuleName = "items"
+
+// Module is the items bounded context: its grpc service and gateway handlers.
+type Module struct {
+	service *service.Item
+}
+
+func NewModule() *Module {
+	return &Module{service: service.New()}
+}
+
+func (m *Module) Name() string {
+	return ModuleName
+}
+
+func (m *Module) RegisterServices(srv *grpc.Server) {
+	itemsV1.RegisterItemServiceServer(srv, m.service)
+}
+
+func (m *Module) RegisterHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+	return itemsV1.RegisterItemServiceHandler(context.Background(), mux, conn)
+}
@@ -0,0 +1,253 @@
+package admin_server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/logger"
+)
+
+const (
+	ServerName = "admin"
+	ConfigKey  = "admin"
+)
+
+type (
+	// Config is the configuration of the admin server. It exposes the configuration and reloads the application,
+	// it listens on the loopback interface unless another host is configured.
+	//
+	//	admin:
+	//	  host: 127.0.0.1
+	//	  port: 8081
+	Config struct {
+		Host string `mapstructure:"host" default:"127.0.0.1"`
+		Port int    `mapstructure:"port" default:"8081" validate:"min=1,max=65535"`
+	}
+
+	// Info is the response of the /info endpoint.
+	Info struct {
+		Name           string            `json:"name"`
+		Version        string            `json:"version,omitempty"`
+		ActiveProfiles []string          `json:"active_profiles"`
+		Build          map[string]string `json:"build,omitempty"`
+	}
+
+	// Loggers is the request and response of the /loggers endpoint.
+	Loggers struct {
+		Level string `json:"level"`
+	}
+
+	server struct {
+		app.UnimplementedServer
+		config    Config
+		server    *http.Server
+		mu        sync.Mutex
+		isStarted bool
+		ready     chan struct{}
+	}
+)
+
+var (
+	log = logger.Log()
+)
+
+func init() {
+	app.RegisterServer(ServerName, NewFactory(app.Default()))
+	app.RegisterConfig(ConfigKey, Config{})
+}
+
+// Install registers an admin server for the application.
+func Install(a *app.App) {
+	a.RegisterServer(ServerName, NewFactory(a))
+	a.RegisterConfig(ConfigKey, Config{})
+}
+
+// NewFactory creates the app.ServerFactory of the admin server reporting the state of the given application.
+func NewFactory(a *app.App) app.ServerFactory {
+	return func(config *app.ApplicationConfig) (app.Server, error) {
+		cfg, err := app.BindFrom[Config](config, ConfigKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &server{
+			config: cfg,
+			server: &http.Server{
+				Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+				Handler: Handler(a, config),
+			},
+			ready: make(chan struct{}),
+		}, nil
+	}
+}
+
+// Handler serves the admin endpoints of the application.
+func Handler(a *app.App, config *app.ApplicationConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, info(a, config))
+	})
+
+	mux.HandleFunc("/env", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, config.RedactedSettings())
+	})
+
+	mux.HandleFunc("/loggers", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			var req Loggers
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if err := logger.SetLevel(req.Level); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			log.Infof("logging level changed to %s", req.Level)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, Loggers{Level: logger.Level()})
+	})
+
+	mux.HandleFunc("/servers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Servers())
+	})
+
+	mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		results, err := a.Reload()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if results == nil {
+			results = []app.ReloadResult{}
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", func(w http.ResponseWriter, r *http.Request) {
+		// the command line may set secrets with --set, they are redacted.
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprint(w, config.RedactString(strings.Join(os.Args, "\x00")))
+	})
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+func info(a *app.App, config *app.ApplicationConfig) Info {
+	i := Info{
+		Name:           a.Name(),
+		Version:        a.Version(),
+		ActiveProfiles: []string{},
+	}
+
+	for _, p := range strings.Split(config.GetString(app.EnvActiveProfiles), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			i.ActiveProfiles = append(i.ActiveProfiles, p)
+		}
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		i.Build = map[string]string{
+			"go_version": bi.GoVersion,
+			"path":       bi.Path,
+			"module":     bi.Main.Path,
+		}
+		if bi.Main.Version != "" {
+			i.Build["module_version"] = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				i.Build[s.Key] = s.Value
+			}
+		}
+	}
+
+	return i
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Reload requires a restart when the listener configuration changed.
+func (s *server) Reload(config *app.ApplicationConfig) error {
+	cfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
+		return err
+	}
+	if cfg != s.config {
+		return fmt.Errorf("%w: %s configuration changed", app.ErrRestartRequired, ConfigKey)
+	}
+	return nil
+}
+
+func (s *server) Start() error {
+	s.mu.Lock()
+	if s.isStarted {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isStarted = true
+	s.mu.Unlock()
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("unable to start admin listener %s: %w", s.server.Addr, err)
+	}
+	close(s.ready)
+
+	// a server stopped before it serves returns http.ErrServerClosed at once and closes the listener.
+	if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("unable to serve admin in listener %s: %w", s.server.Addr, err)
+	}
+	return nil
+}
+
+// Ready is closed once the server is listening.
+func (s *server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Stop gracefully stops the server, open connections are closed when the context deadline expires.
+// A server not started yet is closed, so that it does not serve once started.
+func (s *server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.isStarted
+	s.mu.Unlock()
+	if !started {
+		return s.server.Close()
+	}
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Errorf("error stopping admin server: %v", err)
+		return s.server.Close()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package admin_server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ovargas/wizapp/sdk/app"
+)
+
+func TestNewFactory_address(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "loopback interface by default", want: "127.0.0.1:8081"},
+		{name: "configured host and port", config: "admin:\n  host: 0.0.0.0\n  port: 9090\n", want: "0.0.0.0:9090"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := app.ParseApplicationConfig([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := app.New(app.WithConfig(cfg))
+
+			srv, err := NewFactory(a)(cfg)
+			if err != nil {
+				t.Fatalf("factory error = %v", err)
+			}
+			if got := srv.(*server).server.Addr; got != tt.want {
+				t.Errorf("address = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Stop(t *testing.T) {
+	tests := []struct {
+		name      string
+		stopFirst bool
+	}{
+		{name: "stopped before it starts", stopFirst: true},
+		{name: "stopped once ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &server{server: &http.Server{Addr: "127.0.0.1:0"}, ready: make(chan struct{})}
+			if tt.stopFirst {
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.Start()
+			}()
+			if !tt.stopFirst {
+				<-srv.Ready()
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Start() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start() still serving once stopped")
+			}
+		})
+	}
+}
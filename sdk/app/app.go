@@ -1,20 +1,28 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"github.com/ovargas/wizapp/sdk/health"
+	"github.com/ovargas/wizapp/sdk/logger"
 	"github.com/urfave/cli/v2"
-	"log"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"sync"
-	"syscall"
 )
 
-var (
-	mu sync.RWMutex
+const (
+	FlagConfigPath     = "config-path"
+	FlagActiveProfiles = "active-profiles"
+	FlagSet            = "set"
+	FlagSetFile        = "set-file"
+
+	DefaultConfigPath = "./resources"
+)
 
+var (
 	Name                   = filepath.Base(os.Args[0])
+	Version                = ""
 	Usage                  = "A wizapp application"
 	UsageText              = ""
 	Description            = ""
@@ -35,55 +43,332 @@ type (
 	Setup func(config *ApplicationConfig) error
 )
 
+type (
+	// App is an application instance owning its configuration, servers and components.
+	// The package level functions operate on a default instance, see Default.
+	App struct {
+		mu sync.RWMutex
+
+		name                   string
+		version                string
+		usage                  string
+		usageText              string
+		description            string
+		copyright              string
+		suggest                bool
+		useShortOptionHandling bool
+		enableBashCompletion   bool
+
+		config             *ApplicationConfig
+		serverFactories    map[string]ServerFactory
+		componentFactories map[string]ComponentFactory
+		resourceFactories  map[string]ResourceFactory
+		registrations      map[string]registration
+		commands           []*Command
+
+		states serverStates
+
+		configsMu sync.RWMutex
+		configs   []ConfigDescriptor
+
+		modulesMu sync.RWMutex
+		modules   []Module
+		health    *health.Registry
+
+		resolversMu sync.RWMutex
+		resolvers   map[string]PlaceholderResolver
+
+		sourcesMu sync.RWMutex
+		sources   []ConfigSource
+
+		componentsOnce sync.Once
+		components     map[string]Component
+		componentsErr  error
+
+		runningMu sync.Mutex
+		running   *running
+	}
+
+	// Option configures an App.
+	Option func(*App)
+)
+
+var defaultApp = New()
+
+// New creates an application instance.
+func New(opts ...Option) *App {
+	a := &App{
+		name:                   filepath.Base(os.Args[0]),
+		usage:                  "A wizapp application",
+		suggest:                true,
+		useShortOptionHandling: true,
+		enableBashCompletion:   true,
+		config:                 newApplicationConfig(),
+		serverFactories:        make(map[string]ServerFactory),
+		componentFactories:     make(map[string]ComponentFactory),
+		resourceFactories:      make(map[string]ResourceFactory),
+		registrations:          make(map[string]registration),
+		health:                 health.Default(),
+		resolvers:              builtinResolvers(),
+		sources:                []ConfigSource{springCloudConfigSource{}},
+	}
+	a.RegisterConfig(StartupConfigKey, StartupConfig{})
+	a.RegisterConfig(ShutdownConfigKey, ShutdownConfig{})
+	a.RegisterConfig(logger.ConfigKey, logger.Config{})
+	a.RegisterConfig(SpringCloudConfigKey, SpringCloudConfig{})
+	a.RegisterConfig(WatchConfigKey, WatchConfig{})
+	a.RegisterConfig(ImportConfigKey, []string{})
+	a.RegisterConfig(ProfileGroupKey, map[string][]string{})
+	a.RegisterConfig(ModulesConfigKey, map[string]ModuleConfig{})
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.config.descriptors = a.Configs
+	a.config.modules = a.Modules
+	a.config.resolvers = a.placeholderResolver
+	a.config.sources = a.configSources
+	a.config.OnChange(logger.ConfigKey, func(_, _ interface{}) {
+		a.configureLogger()
+	})
+	return a
+}
+
+// Default returns the application instance used by the package level functions.
+func Default() *App {
+	return defaultApp
+}
+
+// WithName sets the application name.
+func WithName(name string) Option {
+	return func(a *App) {
+		a.name = name
+	}
+}
+
+// WithVersion sets the application version.
+func WithVersion(version string) Option {
+	return func(a *App) {
+		a.version = version
+	}
+}
+
+// WithUsage sets the application usage.
+func WithUsage(usage string) Option {
+	return func(a *App) {
+		a.usage = usage
+	}
+}
+
+// WithDescription sets the application description.
+func WithDescription(description string) Option {
+	return func(a *App) {
+		a.description = description
+	}
+}
+
+// WithConfig sets the application configuration instead of loading it from the files in the config path.
+func WithConfig(config *ApplicationConfig) Option {
+	return func(a *App) {
+		a.config = config
+	}
+}
+
+// WithServer registers a server, see App.RegisterServer.
+func WithServer(name string, factory ServerFactory, opts ...RegisterOption) Option {
+	return func(a *App) {
+		a.RegisterServer(name, factory, opts...)
+	}
+}
+
+// WithComponent registers a component, see App.RegisterComponent.
+func WithComponent(name string, factory ComponentFactory, opts ...RegisterOption) Option {
+	return func(a *App) {
+		a.RegisterComponent(name, factory, opts...)
+	}
+}
+
+// WithModule registers a module, see App.RegisterModule.
+func WithModule(module Module) Option {
+	return func(a *App) {
+		a.RegisterModule(module)
+	}
+}
+
+// WithHealthRegistry sets the registry of the health checks of the application, the default health registry otherwise.
+func WithHealthRegistry(registry *health.Registry) Option {
+	return func(a *App) {
+		a.health = registry
+	}
+}
+
+// Health returns the registry of the health checks of the application, the servers and components installed in the
+// application register and serve their checks through it.
+func (a *App) Health() *health.Registry {
+	return a.health
+}
+
+// WithCommand registers a command, see App.RegisterCommand.
+func WithCommand(cmd *Command, action CommandAction) Option {
+	return func(a *App) {
+		a.RegisterCommand(cmd, action)
+	}
+}
+
+// WithResource registers a resource shared by the commands, see App.RegisterResource.
+func WithResource(name string, factory ResourceFactory) Option {
+	return func(a *App) {
+		a.RegisterResource(name, factory)
+	}
+}
+
+// Run runs the default application with the given command line arguments.
+// The package level Name, Usage, etc. variables are applied to the default application.
 func Run(args []string, setup Setup) error {
+	defaultApp.mu.Lock()
+	defaultApp.name = Name
+	defaultApp.version = Version
+	defaultApp.usage = Usage
+	defaultApp.usageText = UsageText
+	defaultApp.description = Description
+	defaultApp.copyright = Copyright
+	defaultApp.suggest = Suggest
+	defaultApp.useShortOptionHandling = UseShortOptionHandling
+	defaultApp.enableBashCompletion = EnableBashCompletion
+	defaultApp.mu.Unlock()
+
+	return defaultApp.Run(args, setup)
+}
+
+// Run runs the application with the given command line arguments.
+// The returned error carries the reason the application stopped, see ExitCode, its secrets are redacted.
+func (a *App) Run(args []string, setup Setup) error {
+	cfg := a.Config()
+	components, err := a.loadComponents(cfg)
+	if err != nil {
+		return err
+	}
+
+	a.mu.RLock()
 	app := cli.NewApp()
-	app.Name = Name
-	app.Usage = Usage
-	app.UsageText = UsageText
-	app.Description = Description
-	app.Copyright = Copyright
-	app.Suggest = Suggest
-	app.UseShortOptionHandling = UseShortOptionHandling
-	app.EnableBashCompletion = EnableBashCompletion
+	app.Name = a.name
+	app.Version = a.version
+	app.Usage = a.usage
+	app.UsageText = a.usageText
+	app.Description = a.description
+	app.Copyright = a.copyright
+	app.Suggest = a.suggest
+	app.UseShortOptionHandling = a.useShortOptionHandling
+	app.EnableBashCompletion = a.enableBashCompletion
 
+	// The configuration location follows the precedence: command line flag > environment variable > default.
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
-			Name:    "config-path",
-			EnvVars: []string{"CONFIG_PATH"},
-			Value:   "./resources",
+			Name:    FlagConfigPath,
+			Usage:   "Directory of the application.yaml and application-<profile>.yaml files",
+			EnvVars: []string{EnvConfigPath},
+			Value:   DefaultConfigPath,
 		},
 		&cli.StringFlag{
-			Name:    "active-profiles",
-			EnvVars: []string{"ACTIVE_PROFILE"},
+			Name:    FlagActiveProfiles,
+			Usage:   "Comma separated list of active profiles",
+			EnvVars: []string{EnvActiveProfiles, "ACTIVE_PROFILE"},
 			Value:   "",
 		},
+		// The command line values take precedence over the files, the environment and the config sources.
+		&cli.GenericFlag{
+			Name:  FlagSet,
+			Usage: "Sets a configuration key, key=value, may be repeated",
+			Value: &keyValues{},
+		},
+		&cli.GenericFlag{
+			Name:  FlagSetFile,
+			Usage: "Sets a configuration key to the content of a file, key=path, may be repeated",
+			Value: &keyValues{},
+		},
 	}
-
-	cfg := Config()
-
-	components := make(map[string]Component)
-	for k, v := range componentFactories {
-		c, err := v(cfg)
+	app.Before = func(ctx *cli.Context) error {
+		overrides, err := commandLineOverrides(*ctx.Generic(FlagSet).(*keyValues), *ctx.Generic(FlagSetFile).(*keyValues))
 		if err != nil {
-			log.Fatalf("unable to create component %s: %v", k, err)
+			return err
+		}
+		a.config.setCommandLine(overrides)
+		if a.config.loaded {
+			return nil
+		}
+		return a.LoadConfig(ctx.String(FlagConfigPath), splitProfiles(ctx.String(FlagActiveProfiles))...)
+	}
+
+	for _, c := range components {
+		if cmd := c.Command(); cmd != nil {
+			app.Commands = append(app.Commands, cmd)
 		}
-		components[k] = c
-		app.Commands = append(app.Commands, c.Command())
 	}
 
-	app.Commands = append(app.Commands, &cli.Command{
+	app.Commands = append(app.Commands, a.commands...)
+	app.Commands = append(app.Commands, a.configCommand(), &cli.Command{
 		Name:   "start",
 		Usage:  "Start registered servers",
-		Flags:  disableServerFlags(),
-		Action: start(cfg, setup),
+		Flags:  a.disableServerFlags(),
+		Action: a.start(setup),
 	})
+	a.mu.RUnlock()
+
+	return a.config.redactError(app.Run(args))
+}
+
+// Name returns the application name.
+func (a *App) Name() string {
+	return a.name
+}
+
+// Version returns the application version.
+func (a *App) Version() string {
+	return a.version
+}
 
-	return app.Run(args)
+// Config returns the application configuration.
+// Unless the application was created WithConfig, the configuration is empty until Run parses the command line
+// flags and resolves it, the servers and components must read it from their factories or actions.
+func (a *App) Config() *ApplicationConfig {
+	return a.config
 }
 
-func disableServerFlags() []cli.Flag {
+// LoadConfig resolves the application configuration from the files in the configPath and the given profiles,
+// see LoadApplicationConfig.
+func (a *App) LoadConfig(configPath string, profiles ...string) error {
+	err := a.config.load(configPath, profiles)
+	a.configureLogger()
+	return err
+}
+
+// configureLogger applies the logger configuration once the configuration is resolved,
+// the bootstrap logger is used until then. An invalid configuration keeps the bootstrap logger,
+// it is reported along with the rest of the configuration by ValidateConfig.
+func (a *App) configureLogger() {
+	cfg, err := BindFrom[logger.Config](a.config, logger.ConfigKey)
+	if err != nil {
+		return
+	}
+	_ = logger.Configure(&cfg)
+}
+
+// loadDefaultConfig resolves the configuration from the environment when it was not loaded yet,
+// i.e. when the application is served without Run. A configuration given with WithConfig only configures the logger.
+func (a *App) loadDefaultConfig() error {
+	if a.config.loaded {
+		a.configureLogger()
+		return nil
+	}
+	configPath, ok := os.LookupEnv(EnvConfigPath)
+	if !ok {
+		configPath = DefaultConfigPath
+	}
+	return a.LoadConfig(configPath, splitProfiles(os.Getenv(EnvActiveProfiles))...)
+}
+
+func (a *App) disableServerFlags() []cli.Flag {
 	var disableServerFlags []cli.Flag
-	for k := range serverFactories {
+	for k := range a.serverFactories {
 		disableServerFlags = append(disableServerFlags,
 			&cli.BoolFlag{
 				Name:  fmt.Sprintf("disable-%s", k),
@@ -94,61 +379,22 @@ func disableServerFlags() []cli.Flag {
 	return disableServerFlags
 }
 
-func start(cfg *ApplicationConfig, setup Setup) func(ctx *cli.Context) error {
+func (a *App) start(setup Setup) func(ctx *cli.Context) error {
 	return func(ctx *cli.Context) error {
-		if err := setup(cfg); err != nil {
-			return err
-		}
-
-		servers := make(map[string]Server)
-
-		for k, factory := range serverFactories {
+		var disabled []string
+		for k := range a.registered().serverFactories {
 			if ctx.Bool(fmt.Sprintf("disable-%s", k)) {
-				continue
+				disabled = append(disabled, k)
 			}
-
-			srv, err := factory(cfg)
-			if err != nil {
-				log.Fatalf("unable to start \"%s\" server: %v", k, err)
-			}
-			servers[k] = srv
 		}
 
-		for _, s := range servers {
-			go func(srv Server) {
-				if err := srv.Start(); err != nil {
-					log.Fatalf("unable to start server: %v", err)
-				}
-			}(s)
-		}
-		wait(interruptCh(), servers)
+		serveCtx, cancel := context.WithCancel(ctx.Context)
+		defer cancel()
+		served := make(chan struct{})
+		defer close(served)
+		go interruptOnSignal(served, cancel)
+		go a.reloadOnSignal(serveCtx)
 
-		return nil
+		return a.Serve(serveCtx, setup, WithoutServers(disabled...))
 	}
 }
-
-func wait(interruptCh <-chan interface{}, servers map[string]Server) {
-	select {
-	case s := <-interruptCh:
-		log.Printf("application stopped. Signal %v", s)
-		for k, s := range servers {
-			if err := s.Stop(); err != nil {
-				log.Printf("error stopping %s server: %v", k, err)
-			}
-		}
-	}
-}
-
-func interruptCh() <-chan interface{} {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	ret := make(chan interface{}, 1)
-	go func() {
-		s := <-c
-		ret <- s
-		close(ret)
-	}()
-
-	return ret
-}
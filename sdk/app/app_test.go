@@ -0,0 +1,109 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApp_Run_configLocation(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"resources/application.yaml":        "location: default\nprofile: none\n",
+		"flag/application.yaml":             "location: flag\nprofile: none\n",
+		"env/application.yaml":              "location: env\nprofile: none\n",
+		"resources/application-flag.yaml":   "profile: flag\n",
+		"resources/application-env.yaml":    "profile: env\n",
+		"resources/application-legacy.yaml": "profile: legacy\n",
+	})
+
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		wantLocation string
+		wantProfile  string
+	}{
+		{
+			name:         "defaults",
+			wantLocation: "default",
+			wantProfile:  "none",
+		},
+		{
+			name:         "config path from the environment",
+			env:          map[string]string{EnvConfigPath: filepath.Join(dir, "env")},
+			wantLocation: "env",
+			wantProfile:  "none",
+		},
+		{
+			name:         "config path flag over the environment",
+			args:         []string{"--" + FlagConfigPath, filepath.Join(dir, "flag")},
+			env:          map[string]string{EnvConfigPath: filepath.Join(dir, "env")},
+			wantLocation: "flag",
+			wantProfile:  "none",
+		},
+		{
+			name:         "active profiles from the environment",
+			env:          map[string]string{EnvActiveProfiles: "env"},
+			wantLocation: "default",
+			wantProfile:  "env",
+		},
+		{
+			name:         "active profiles from the legacy environment variable",
+			env:          map[string]string{"ACTIVE_PROFILE": "legacy"},
+			wantLocation: "default",
+			wantProfile:  "legacy",
+		},
+		{
+			name:         "active profiles flag over the environment",
+			args:         []string{"--" + FlagActiveProfiles, "flag"},
+			env:          map[string]string{EnvActiveProfiles: "env", "ACTIVE_PROFILE": "legacy"},
+			wantLocation: "default",
+			wantProfile:  "flag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the default config path is relative to the working directory.
+			chdir(t, dir)
+			for _, key := range []string{EnvConfigPath, EnvActiveProfiles, "ACTIVE_PROFILE"} {
+				t.Setenv(key, tt.env[key])
+				if _, ok := tt.env[key]; !ok {
+					_ = os.Unsetenv(key)
+				}
+			}
+
+			a := New(WithName(t.Name()))
+			var location, profile string
+			a.RegisterCommand(&Command{Name: "show"}, func(ctx *CommandContext) error {
+				location = ctx.Config.GetString("location")
+				profile = ctx.Config.GetString("profile")
+				return nil
+			})
+
+			args := append(append([]string{"test"}, tt.args...), "show")
+			if err := a.Run(args, nil); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if location != tt.wantLocation {
+				t.Errorf("Run() location = %q, want %q", location, tt.wantLocation)
+			}
+			if profile != tt.wantProfile {
+				t.Errorf("Run() profile = %q, want %q", profile, tt.wantProfile)
+			}
+		})
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
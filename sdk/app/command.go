@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+)
+
+type (
+	// CommandAction is the action of a command registered with RegisterCommand.
+	CommandAction func(ctx *CommandContext) error
+
+	// ResourceFactory creates a resource shared by the commands, e.g. a datasource or a client.
+	// The resource is closed once the command completes when it implements io.Closer or a Close method
+	// without result.
+	ResourceFactory func(config *ApplicationConfig) (interface{}, error)
+
+	// CommandContext is the context of a command registered with RegisterCommand: the parsed command line,
+	// the resolved configuration and the registered resources, created on first use.
+	CommandContext struct {
+		*cli.Context
+		Config *ApplicationConfig
+
+		app       *App
+		mu        sync.Mutex
+		resources map[string]interface{}
+		created   []interface{}
+	}
+)
+
+// RegisterCommand registers a command of the default application, see App.RegisterCommand.
+func RegisterCommand(cmd *Command, action CommandAction) {
+	defaultApp.RegisterCommand(cmd, action)
+}
+
+// RegisterCommand registers a command next to start and the component commands, e.g. a maintenance task.
+// The action of the command is replaced by the given one, it receives the resolved configuration and the
+// registered resources, see RegisterResource.
+//
+//	app.RegisterCommand(&app.Command{Name: "backfill", Usage: "Backfill the item prices"}, func(ctx *app.CommandContext) error {
+//		ds, err := datasource.FromCommand(ctx)
+//		...
+//	})
+func (a *App) RegisterCommand(cmd *Command, action CommandAction) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, c := range a.commands {
+		if c.Name == cmd.Name {
+			log.Fatalf("Command %s already registered", cmd.Name)
+		}
+	}
+
+	cmd.Action = a.commandAction(action)
+	a.commands = append(a.commands, cmd)
+}
+
+// RegisterResource registers a resource factory of the default application, see App.RegisterResource.
+func RegisterResource(name string, factory ResourceFactory) {
+	defaultApp.RegisterResource(name, factory)
+}
+
+// RegisterResource registers a resource factory under the given name, the resource is created once per command,
+// the first time the command requests it, see CommandContext.Resource.
+func (a *App) RegisterResource(name string, factory ResourceFactory) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.resourceFactories[name]; ok {
+		log.Fatalf("Resource %s already registered", name)
+	}
+	a.resourceFactories[name] = factory
+}
+
+// Resource returns the resource registered under the name, cast to T.
+//
+//	db, err := app.Resource[*datasource.Datasource](ctx, datasource.ResourceName)
+func Resource[T any](ctx *CommandContext, name string) (T, error) {
+	var zero T
+	r, err := ctx.Resource(name)
+	if err != nil {
+		return zero, err
+	}
+	t, ok := r.(T)
+	if !ok {
+		return zero, fmt.Errorf("resource %s is a %T, not a %s", name, r, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return t, nil
+}
+
+// Resource returns the resource registered under the name, it is created on first use.
+func (c *CommandContext) Resource(name string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r, ok := c.resources[name]; ok {
+		return r, nil
+	}
+
+	c.app.mu.RLock()
+	factory, ok := c.app.resourceFactories[name]
+	c.app.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("resource %s is not registered", name)
+	}
+
+	r, err := factory(c.Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create resource %s: %w", name, err)
+	}
+	c.resources[name] = r
+	c.created = append(c.created, r)
+	return r, nil
+}
+
+// close closes the created resources in reverse order.
+func (c *CommandContext) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for i := len(c.created) - 1; i >= 0; i-- {
+		switch r := c.created[i].(type) {
+		case io.Closer:
+			if err := r.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		case interface{ Close() }:
+			r.Close()
+		}
+	}
+	c.resources, c.created = nil, nil
+	return joinErrors(errs)
+}
+
+// commandAction runs the action with a new CommandContext, then closes the resources it created.
+func (a *App) commandAction(action CommandAction) cli.ActionFunc {
+	return func(ctx *cli.Context) (err error) {
+		cc := &CommandContext{Context: ctx, Config: a.Config(), app: a, resources: make(map[string]interface{})}
+		defer func() {
+			if closeErr := cc.close(); err == nil {
+				err = closeErr
+			}
+		}()
+		return action(cc)
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type (
+	// closerResource is a resource implementing io.Closer.
+	closerResource struct {
+		name    string
+		journal *journal
+		err     error
+	}
+
+	// quietResource is a resource with a Close method without result.
+	quietResource struct {
+		name    string
+		journal *journal
+	}
+)
+
+func (r *closerResource) Close() error {
+	r.journal.add("close " + r.name)
+	return r.err
+}
+
+func (r *quietResource) Close() {
+	r.journal.add("close " + r.name)
+}
+
+func resourceFactory(j *journal, name string, resource interface{}, err error) ResourceFactory {
+	return func(*ApplicationConfig) (interface{}, error) {
+		j.add("create " + name)
+		return resource, err
+	}
+}
+
+func newResourceApp(j *journal) *App {
+	a := New()
+	a.RegisterResource("db", resourceFactory(j, "db", &closerResource{name: "db", journal: j}, nil))
+	a.RegisterResource("cache", resourceFactory(j, "cache", &quietResource{name: "cache", journal: j}, nil))
+	a.RegisterResource("plain", resourceFactory(j, "plain", "plain", nil))
+	a.RegisterResource("failing", resourceFactory(j, "failing", &closerResource{
+		name:    "failing",
+		journal: j,
+		err:     errors.New("connection reset"),
+	}, nil))
+	a.RegisterResource("broken", resourceFactory(j, "broken", nil, errors.New("no route to host")))
+	return a
+}
+
+func TestCommandContext_Resource(t *testing.T) {
+	tests := []struct {
+		name          string
+		resources     []string
+		actionErr     error
+		wantErr       string
+		wantLifecycle string
+	}{
+		{
+			name:          "created on first use",
+			wantLifecycle: "",
+		},
+		{
+			name:          "created once per command",
+			resources:     []string{"db", "db"},
+			wantLifecycle: "create db,close db",
+		},
+		{
+			name:          "closed in reverse order",
+			resources:     []string{"db", "plain", "cache"},
+			wantLifecycle: "create db,create plain,create cache,close cache,close db",
+		},
+		{
+			name:          "not registered",
+			resources:     []string{"db", "missing"},
+			wantErr:       "resource missing is not registered",
+			wantLifecycle: "create db,close db",
+		},
+		{
+			name:          "not created",
+			resources:     []string{"db", "broken"},
+			wantErr:       "unable to create resource broken: no route to host",
+			wantLifecycle: "create db,create broken,close db",
+		},
+		{
+			name:          "close error",
+			resources:     []string{"db", "failing", "cache"},
+			wantErr:       "connection reset",
+			wantLifecycle: "create db,create failing,create cache,close cache,close failing,close db",
+		},
+		{
+			name:          "action error over the close error",
+			resources:     []string{"failing"},
+			actionErr:     errors.New("backfill failed"),
+			wantErr:       "backfill failed",
+			wantLifecycle: "create failing,close failing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{}
+			a := newResourceApp(j)
+
+			err := a.commandAction(func(ctx *CommandContext) error {
+				for _, name := range tt.resources {
+					first, err := ctx.Resource(name)
+					if err != nil {
+						return err
+					}
+					if again, _ := ctx.Resource(name); again != first {
+						t.Errorf("Resource(%s) = %v, want the instance %v", name, again, first)
+					}
+				}
+				return tt.actionErr
+			})(nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("command error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("command error = %v", err)
+			}
+			if got := j.String(); got != tt.wantLifecycle {
+				t.Errorf("lifecycle = %q, want %q", got, tt.wantLifecycle)
+			}
+		})
+	}
+}
+
+func TestCommandContext_Resource_perCommand(t *testing.T) {
+	j := &journal{}
+	a := newResourceApp(j)
+	a.RegisterResource("conn", func(*ApplicationConfig) (interface{}, error) {
+		j.add("create conn")
+		return &closerResource{name: "conn", journal: j}, nil
+	})
+
+	var created []interface{}
+	action := a.commandAction(func(ctx *CommandContext) error {
+		r, err := ctx.Resource("conn")
+		created = append(created, r)
+		return err
+	})
+	for i := 0; i < 2; i++ {
+		if err := action(nil); err != nil {
+			t.Fatalf("command error = %v", err)
+		}
+	}
+
+	if want := "create conn,close conn,create conn,close conn"; j.String() != want {
+		t.Errorf("lifecycle = %q, want %q", j.String(), want)
+	}
+	if len(created) != 2 || created[0] == created[1] {
+		t.Errorf("created = %v, want a resource per command", created)
+	}
+}
+
+func TestResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		get     func(ctx *CommandContext) (interface{}, error)
+		wantErr string
+	}{
+		{
+			name: "concrete type",
+			get: func(ctx *CommandContext) (interface{}, error) {
+				return Resource[*closerResource](ctx, "db")
+			},
+		},
+		{
+			name: "interface",
+			get: func(ctx *CommandContext) (interface{}, error) {
+				return Resource[io.Closer](ctx, "db")
+			},
+		},
+		{
+			name: "type mismatch",
+			get: func(ctx *CommandContext) (interface{}, error) {
+				return Resource[string](ctx, "db")
+			},
+			wantErr: "resource db is a *app.closerResource, not a string",
+		},
+		{
+			name: "interface mismatch",
+			get: func(ctx *CommandContext) (interface{}, error) {
+				return Resource[io.Closer](ctx, "cache")
+			},
+			wantErr: "resource cache is a *app.quietResource, not a io.Closer",
+		},
+		{
+			name: "not registered",
+			get: func(ctx *CommandContext) (interface{}, error) {
+				return Resource[string](ctx, "missing")
+			},
+			wantErr: "resource missing is not registered",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{}
+			a := newResourceApp(j)
+
+			err := a.commandAction(func(ctx *CommandContext) error {
+				r, err := tt.get(ctx)
+				if err != nil {
+					return err
+				}
+				if r == nil {
+					t.Error("Resource() = nil, want the resource")
+				}
+				return nil
+			})(nil)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Resource() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resource() error = %v", err)
+			}
+		})
+	}
+}
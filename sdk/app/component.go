@@ -10,22 +10,30 @@ type (
 		mustImplementComponent()
 	}
 
+	// ComponentFactory creates a component when the application runs, before the command line is parsed.
+	// The configuration is resolved once the command line flags are parsed, the component must read it
+	// from its command actions or Initializer.
 	ComponentFactory func(config *ApplicationConfig) (Component, error)
 )
 
-var (
-	componentFactories = make(map[string]ComponentFactory)
-)
+// RegisterComponent registers a component factory under the given name in the default application.
+// The options allow declaring the servers or components it depends on, see DependsOn.
+func RegisterComponent(name string, factory ComponentFactory, opts ...RegisterOption) {
+	defaultApp.RegisterComponent(name, factory, opts...)
+}
 
-func RegisterComponent(name string, factory ComponentFactory) {
-	mu.Lock()
-	defer mu.Unlock()
+// RegisterComponent registers a component factory under the given name.
+// The options allow declaring the servers or components it depends on, see DependsOn.
+func (a *App) RegisterComponent(name string, factory ComponentFactory, opts ...RegisterOption) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	if _, ok := componentFactories[name]; ok {
+	if _, ok := a.componentFactories[name]; ok {
 		log.Fatalf("Component %s already registered", name)
 	}
 
-	componentFactories[name] = factory
+	a.componentFactories[name] = factory
+	a.registrations[name] = newRegistration(opts)
 }
 
 type UnimplementedComponent struct{}
@@ -2,10 +2,9 @@ package app
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 
@@ -19,144 +18,284 @@ const (
 	EnvSpringCloudConfigUri = "SPRING_CLOUD_CONFIG_URI"
 	EnvConfigPath           = "CONFIG_PATH"
 	EnvAppName              = "APP_NAME"
-)
-
-var (
-	once              sync.Once
-	applicationConfig *ApplicationConfig
-	re                = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)((:)(.+?))?\}`)
+	EnvEncryptKey           = "ENCRYPT_KEY"
+	EnvEncryptKeyFile       = "ENCRYPT_KEY_FILE"
 )
 
 type DecoderConfigOption func(*mapstructure.DecoderConfig)
 
 type ApplicationConfig struct {
-	viper *viper.Viper
+	mu       sync.RWMutex
+	reloadMu sync.Mutex
+	viper    *viper.Viper
+	// origins holds the source of the value of each key, before the environment variables are applied.
+	origins map[string]string
+	// pinned holds the keys of the config sources and the command line taking precedence over the environment variables.
+	pinned map[string]bool
+	// raw holds the value of each key before the placeholders are resolved.
+	raw map[string]interface{}
+	// overrides holds the values set with Set, they are kept when the configuration is reloaded.
+	overrides map[string]interface{}
+	// commandLine holds the values of the --set and --set-file flags, they are resolved with the configuration.
+	commandLine map[string]commandLineValue
+	// loaded is false until the configuration is resolved, see App.LoadConfig.
+	loaded bool
+	// configPath and profiles are the arguments of load, the configuration is reloaded from them.
+	configPath string
+	profiles   []string
+	reloadable bool
+	// descriptors returns the configurations registered in the application, see Bind.
+	descriptors func() []ConfigDescriptor
+	// modules returns the modules registered in the application, see Modules.
+	modules func() []Module
+	// resolvers returns the placeholder resolvers registered in the application, see RegisterPlaceholderResolver.
+	resolvers resolverLookup
+	// sources returns the config sources registered in the application, see RegisterConfigSource.
+	sources     func() []ConfigSource
+	subscribers []subscriber
+}
+
+// newApplicationConfig creates an empty configuration to be resolved later by load.
+func newApplicationConfig() *ApplicationConfig {
+	return &ApplicationConfig{
+		viper:     viper.New(),
+		origins:   make(map[string]string),
+		raw:       make(map[string]interface{}),
+		overrides: make(map[string]interface{}),
+	}
+}
+
+// LoadApplicationConfig loads the application and application-<profile> files found in the configPath, ./config
+// or . directories, in the yaml, json, toml, properties or env format, and the files they import, see ImportConfigKey.
+// Then the registered config sources, see RegisterConfigSource, including the spring cloud config server when
+// spring.cloud.config.uri or SPRING_CLOUD_CONFIG_URI is set, see SpringCloudConfig.
+// When no profiles are given, the ACTIVE_PROFILES environment variable or the active_profiles key of the
+// application file are used.
+func LoadApplicationConfig(configPath string, profiles ...string) *ApplicationConfig {
+	c := newApplicationConfig()
+	if err := c.load(configPath, profiles); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return c
+}
+
+// load resolves the configuration in place, it returns the errors of the required imports, of the config sources,
+// e.g. a fail fast spring cloud config server, and the values that could not be decrypted or resolved.
+func (c *ApplicationConfig) load(configPath string, profiles []string) error {
+	c.mu.RLock()
+	commandLine := c.commandLine
+	c.mu.RUnlock()
+	r, err := loadConfig(configPath, profiles, commandLine, c.configSources(), c.placeholderResolvers())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configPath = configPath
+	c.profiles = profiles
+	c.loaded = true
+	c.reloadable = true
+	c.apply(r)
+	return err
+}
+
+// apply replaces the resolved configuration, the overrides are set again.
+func (c *ApplicationConfig) apply(r *resolvedConfig) {
+	for key, value := range c.overrides {
+		r.viper.Set(key, value)
+		r.origins[key] = "override"
+		r.raw[key] = value
+	}
+	c.viper = r.viper
+	c.origins = r.origins
+	c.raw = r.raw
+	c.pinned = r.pinned
 }
 
-func LoadApplicationConfig(configPath string) *ApplicationConfig {
+// resolvedConfig is the configuration resolved by loadConfig.
+type resolvedConfig struct {
+	viper     *viper.Viper
+	origins   map[string]string
+	raw       map[string]interface{}
+	pinned    map[string]bool
+	resolvers resolverLookup
+	// commandLine holds the values of the command line, the config sources do not override them.
+	commandLine map[string]commandLineValue
+}
+
+// loadConfig resolves the configuration from the files of the profiles and the config sources, see ConfigSource.
+// The sources with a priority lower than ConfigPriorityFiles are loaded before the files. The command line values
+// take precedence over every source, they are set first for the sources to read them, and again once every source
+// is merged.
+func loadConfig(configPath string, profiles []string, commandLine map[string]commandLineValue,
+	sources []ConfigSource, resolvers resolverLookup) (*resolvedConfig, error) {
 	v := viper.New()
 	v.SetEnvPrefix("")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.SetDefault(EnvActiveProfiles, "")
 	v.AutomaticEnv()
 	v.SetConfigType("yaml")
-	loadFile(v, configPath)
-	loadSpringCloudConfig(v)
-	v.AutomaticEnv()
-	replaceConfigurationVariables(v)
-	return &ApplicationConfig{
-		viper: v,
+	r := &resolvedConfig{
+		viper:       v,
+		origins:     make(map[string]string),
+		pinned:      make(map[string]bool),
+		resolvers:   resolvers,
+		commandLine: commandLine,
 	}
-}
+	r.setCommandLine()
 
-func replaceSingleVariable(v *viper.Viper, value string) string {
-	groups := re.FindAllStringSubmatch(value, -1)
-	for _, group := range groups {
-		match := group[0]
-		variable := group[1]
-		defaultValue := group[4]
-		variableValue := v.GetString(variable)
-		if variableValue == "" {
-			variableValue = defaultValue
+	var errs []error
+	i := 0
+	for ; i < len(sources) && sources[i].Priority() < ConfigPriorityFiles; i++ {
+		if err := r.mergeSource(sources[i]); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	if err := loadFile(v, configPath, profiles, r.origins); err != nil {
+		errs = append(errs, err)
+	}
+	for ; i < len(sources); i++ {
+		if err := r.mergeSource(sources[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	r.setCommandLine()
 
-		value = strings.Replace(value, match, variableValue, -1)
+	v.AutomaticEnv()
+	r.raw = rawValues(v)
+	if err := resolveValues(v, resolvers); err != nil {
+		errs = append(errs, err)
 	}
-	return value
+	return r, joinErrors(errs)
 }
 
-func replaceConfigurationVariables(v *viper.Viper) {
-	for _, k := range v.AllKeys() {
-		value := v.Get(k)
-		switch value.(type) {
-		case []interface{}:
-			v.Set(k, replaceConfigurationVariablesInSlice(v, value.([]interface{})))
-		case map[interface{}]interface{}:
-			v.Set(k, replaceConfigurationVariablesInMap(v, value.(map[interface{}]interface{})))
-		default:
-			v.Set(k, replaceSingleVariable(v, fmt.Sprintf("%v", value)))
-		}
+// setCommandLine sets and pins the command line values, recording their origin over the one of the sources.
+func (r *resolvedConfig) setCommandLine() {
+	for key, value := range r.commandLine {
+		r.viper.Set(key, value.value)
+		r.pinned[key] = true
+		r.origins[key] = value.origin
 	}
 }
 
-func replaceConfigurationVariablesInSlice(v *viper.Viper, slice []interface{}) []interface{} {
-	for i, item := range slice {
-		switch item.(type) {
-		case string:
-			slice[i] = replaceSingleVariable(v, item.(string))
-		case map[interface{}]interface{}:
-			slice[i] = replaceConfigurationVariablesInMap(v, item.(map[interface{}]interface{}))
-		}
+// joinErrors returns nil, the error, or an error listing the errors.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
 	}
-
-	return slice
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return errors.New(strings.Join(msgs, "\n"))
 }
 
-func replaceConfigurationVariablesInMap(v *viper.Viper, object map[interface{}]interface{}) map[interface{}]interface{} {
-	for key, value := range object {
-		switch value.(type) {
-		case string:
-			object[key] = replaceSingleVariable(v, value.(string))
-		case []interface{}:
-			object[key] = replaceConfigurationVariablesInSlice(v, value.([]interface{}))
-		case map[interface{}]interface{}:
-			object[key] = replaceConfigurationVariablesInMap(v, object)
-		}
+// ParseApplicationConfig creates the configuration from the given yaml document, the environment
+// variables and placeholders are resolved as in LoadApplicationConfig.
+func ParseApplicationConfig(yaml []byte) (*ApplicationConfig, error) {
+	v := viper.New()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(yaml)); err != nil {
+		return nil, err
+	}
+	origins := make(map[string]string)
+	recordOrigins(origins, "", v.AllSettings(), "inline configuration")
+	raw := rawValues(v)
+	if err := resolveValues(v, defaultPlaceholderResolver); err != nil {
+		return nil, err
 	}
+	return &ApplicationConfig{
+		viper:     v,
+		origins:   origins,
+		pinned:    make(map[string]bool),
+		raw:       raw,
+		overrides: make(map[string]interface{}),
+		loaded:    true,
+	}, nil
+}
 
-	return object
+// resolveValues decrypts the {cipher} values, then resolves the placeholders.
+func resolveValues(v *viper.Viper, resolvers resolverLookup) error {
+	if err := decryptValues(v); err != nil {
+		return err
+	}
+	return resolvePlaceholders(v, resolvers)
 }
 
-// loadFile loads the configuration from a file.
-func loadFile(v *viper.Viper, configPath string) {
-	v.SetConfigName("application")
-	v.AddConfigPath(configPath)
-	v.AddConfigPath("./config")
-	v.AddConfigPath(".")
+// loadFile loads the configuration from the application file and the files of the profiles, then the files they
+// import, see ImportConfigKey. The documents activated by a profile are merged once the profiles are known,
+// see ProfileActivationKey. The active profiles, read from the configuration when none is given and expanded with
+// their groups, are set in ACTIVE_PROFILES. It returns the errors of the required imports and activation expressions.
+func loadFile(v *viper.Viper, configPath string, profiles []string, origins map[string]string) error {
+	paths := []string{configPath, "./config", "."}
+	l := &configLoader{v: v, origins: origins, seen: make(map[string]bool)}
 
-	if configPath != "" {
-		configPath = filepath.Dir(v.GetString(configPath))
-		v.AddConfigPath(configPath)
+	err := l.mergeConfigFile("application", paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load config file application, error: %s\n", err)
 	}
 
-	err := v.MergeInConfig()
-	if err != nil {
-		fmt.Printf("unable to load config file application.yaml, error: %s\n", err)
+	if len(profiles) == 0 {
+		profiles = splitProfiles(v.GetString(EnvActiveProfiles))
 	}
-	profiles := v.GetString(EnvActiveProfiles)
-	for _, p := range strings.Split(profiles, ",") {
-		if p == "" {
-			continue
+	profiles = expandProfiles(profiles, profileGroups(v))
+	if len(profiles) > 0 {
+		v.Set(EnvActiveProfiles, strings.Join(profiles, ","))
+	}
+	l.activate(profiles)
+
+	for _, p := range profiles {
+		// the profiles may only activate documents of the application file.
+		err = l.mergeConfigFile(fmt.Sprintf("application-%s", p), paths)
+		if err != nil && !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+			fmt.Fprintf(os.Stderr, "unable to load config file application-%s, error: %s\n", p, err)
 		}
-		v.SetConfigName(fmt.Sprintf("application-%s", p))
-		err = v.MergeInConfig()
-		if err != nil {
-			fmt.Printf("unable to load config file application-%s.yaml, error: %s\n", p, err)
+	}
+
+	if len(l.errs) > 0 {
+		msgs := make([]string, 0, len(l.errs))
+		for _, e := range l.errs {
+			msgs = append(msgs, e.Error())
 		}
+		return fmt.Errorf("unable to load configuration:\n  %s", strings.Join(msgs, "\n  "))
 	}
+	return nil
 }
 
-// loadSpringCloudConfig loads the configuration from a spring cloud configuration server.
-func loadSpringCloudConfig(v *viper.Viper) {
-	if !v.IsSet(EnvSpringCloudConfigUri) {
-		return
+// splitProfiles splits a comma separated list of profiles.
+func splitProfiles(profiles string) []string {
+	var split []string
+	for _, p := range strings.Split(profiles, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			split = append(split, p)
+		}
 	}
+	return split
+}
 
-	v.SetDefault(EnvAppName, "app")
-
-	serverURL := v.GetString(EnvSpringCloudConfigUri)
-	profiles := v.GetString(EnvActiveProfiles)
-	appName := v.GetString(EnvAppName)
-	if profiles != "" {
-		profiles = "-" + profiles
+// recordOrigins records the source as the origin of every key of the settings.
+func recordOrigins(origins map[string]string, prefix string, settings map[string]interface{}, source string) {
+	for k, value := range settings {
+		key := joinKey(prefix, strings.ToLower(k))
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			recordOrigins(origins, key, m, source)
+			continue
+		}
+		origins[key] = source
 	}
-	url := fmt.Sprintf("%s/%s%s.yaml", serverURL, appName, profiles)
-	v.SetConfigName("remote")
-	resp := fetchConfiguration(url)
-	err := v.MergeConfig(bytes.NewBuffer(resp))
-	if err != nil {
-		fmt.Printf("could not read config at :%s, viper error: %v.\n", url, err)
+}
+
+// rawValues returns the values of every key before the placeholders are resolved.
+func rawValues(v *viper.Viper) map[string]interface{} {
+	raw := make(map[string]interface{})
+	for _, k := range v.AllKeys() {
+		raw[k] = v.Get(k)
 	}
+	return raw
 }
 
 func toViperOpts(opts []DecoderConfigOption) []viper.DecoderConfigOption {
@@ -167,33 +306,93 @@ func toViperOpts(opts []DecoderConfigOption) []viper.DecoderConfigOption {
 	return viperOpts
 }
 
+// current returns the resolved configuration, it is replaced as a whole when the configuration is reloaded.
+func (c *ApplicationConfig) current() *viper.Viper {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.viper
+}
+
 func (c *ApplicationConfig) Unmarshal(rawVal interface{}, opts ...DecoderConfigOption) error {
-	return c.viper.Unmarshal(rawVal, toViperOpts(opts)...)
+	return c.current().Unmarshal(rawVal, toViperOpts(opts)...)
 }
 
 func (c *ApplicationConfig) UnmarshalKey(key string, rawVal interface{}, opts ...DecoderConfigOption) error {
-	return c.viper.UnmarshalKey(key, rawVal, toViperOpts(opts)...)
+	return c.current().UnmarshalKey(key, rawVal, toViperOpts(opts)...)
 }
 
 func (c *ApplicationConfig) Settings() map[string]interface{} {
-	return c.viper.AllSettings()
+	return c.current().AllSettings()
+}
+
+// Set overrides the value of the key, the override is kept when the configuration is reloaded.
+func (c *ApplicationConfig) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.viper.Set(key, value)
+	key = strings.ToLower(key)
+	c.origins[key] = "override"
+	c.raw[key] = value
+	c.overrides[key] = value
+}
+
+// IsSet reports whether the key has a value.
+func (c *ApplicationConfig) IsSet(key string) bool {
+	return c.current().IsSet(key)
+}
+
+// Source returns the source that produced the value of the key: a configuration file, an environment variable,
+// the spring cloud config server, etc. It returns an empty string when the key is not set.
+func (c *ApplicationConfig) Source(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key = strings.ToLower(key)
+	if origin := c.origins[key]; origin == "override" || c.pinned[key] {
+		return origin
+	}
+	if name := envName(key); name != "" {
+		if _, ok := os.LookupEnv(name); ok {
+			return fmt.Sprintf("environment variable %s", name)
+		}
+	}
+	if origin, ok := c.origins[key]; ok {
+		return origin
+	}
+	if c.viper.IsSet(key) {
+		return "default"
+	}
+	return ""
+}
+
+// RawValue returns the value of the key before its placeholders are resolved.
+func (c *ApplicationConfig) RawValue(key string) interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.raw[strings.ToLower(key)]
+}
+
+// envName returns the environment variable name bound to the key.
+func envName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_").Replace(key))
 }
 
 func (c *ApplicationConfig) GetString(key string) string {
-	return c.viper.GetString(key)
+	return c.current().GetString(key)
 }
 
 func (c *ApplicationConfig) Get(key string) interface{} {
-	return c.viper.Get(key)
+	return c.current().Get(key)
 }
 
+// Config returns the configuration of the default application.
 func Config() *ApplicationConfig {
-	once.Do(func() {
-		applicationConfig = LoadApplicationConfig(os.Getenv(EnvConfigPath))
-	})
-	return applicationConfig
+	return defaultApp.Config()
 }
 
+// Viper returns the resolved configuration, it is replaced when the configuration is reloaded and it must not be
+// modified, use Set instead.
 func (c *ApplicationConfig) Viper() *viper.Viper {
-	return c.viper
+	return c.current()
 }
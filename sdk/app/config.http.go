@@ -1,28 +1,351 @@
 package app
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
 )
 
-// fetchConfiguration makes an HTTP request to fetch configuration from the config server.
-func fetchConfiguration(url string) []byte {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("unable to fetch configuration from %s\n", url)
+// SpringCloudConfigKey is the configuration key of the spring cloud config client.
+const SpringCloudConfigKey = "spring.cloud.config"
+
+type (
+	// SpringCloudConfig configures the client of a spring cloud config server. The client settings are read from the
+	// local configuration files and the environment variables, e.g. SPRING_CLOUD_CONFIG_URI.
+	//
+	//	spring:
+	//	  cloud:
+	//	    config:
+	//	      uri: https://config.example.com # comma separated URIs are tried in order
+	//	      name: item                      # default: spring.application.name, APP_NAME or app
+	//	      profile: dev                    # default: the active profiles or default
+	//	      label: main
+	//	      username: user                  # basic authentication
+	//	      password: ${file:///run/secrets/config_password}
+	//	      token: ${env://CONFIG_TOKEN}    # bearer authentication
+	//	      timeout: 10s
+	//	      fail_fast: true                 # abort the startup when the server can not be reached
+	//	      retry:                          # retries are only made when fail_fast is enabled
+	//	        max_attempts: 6
+	//	        initial_interval: 1s
+	//	        multiplier: 1.1
+	//	        max_interval: 2s
+	//	      tls:
+	//	        ca_file: /etc/ssl/config-ca.pem
+	SpringCloudConfig struct {
+		URI      string                 `mapstructure:"uri"`
+		Name     string                 `mapstructure:"name"`
+		Profile  string                 `mapstructure:"profile"`
+		Label    string                 `mapstructure:"label"`
+		Username string                 `mapstructure:"username"`
+		Password string                 `mapstructure:"password"`
+		Token    string                 `mapstructure:"token"`
+		Timeout  time.Duration          `mapstructure:"timeout" default:"10s"`
+		FailFast bool                   `mapstructure:"fail_fast"`
+		Retry    SpringCloudConfigRetry `mapstructure:"retry"`
+		TLS      SpringCloudConfigTLS   `mapstructure:"tls"`
+	}
+
+	// SpringCloudConfigRetry configures the exponential backoff between the attempts to fetch the configuration.
+	SpringCloudConfigRetry struct {
+		MaxAttempts     int           `mapstructure:"max_attempts" default:"6" validate:"min=1"`
+		InitialInterval time.Duration `mapstructure:"initial_interval" default:"1s"`
+		Multiplier      float64       `mapstructure:"multiplier" default:"1.1" validate:"min=1"`
+		MaxInterval     time.Duration `mapstructure:"max_interval" default:"2s"`
+	}
+
+	// SpringCloudConfigTLS configures the TLS connection to the config server.
+	SpringCloudConfigTLS struct {
+		InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+		CAFile             string `mapstructure:"ca_file"`
+		CertFile           string `mapstructure:"cert_file"`
+		KeyFile            string `mapstructure:"key_file"`
+	}
+
+	// springEnvironment is the response of the /{application}/{profile}/{label} endpoint.
+	springEnvironment struct {
+		Name            string                 `json:"name"`
+		Profiles        []string               `json:"profiles"`
+		Label           string                 `json:"label"`
+		Version         string                 `json:"version"`
+		PropertySources []springPropertySource `json:"propertySources"`
+	}
+
+	springPropertySource struct {
+		Name   string                 `json:"name"`
+		Source map[string]interface{} `json:"source"`
+	}
+)
+
+// springCloudConfigSource loads the configuration from a spring cloud config server, see SpringCloudConfig.
+type springCloudConfigSource struct{}
+
+func (springCloudConfigSource) Name() string {
+	return "spring cloud config"
+}
+
+func (springCloudConfigSource) Priority() int {
+	return ConfigPrioritySpringCloudConfig
+}
+
+// Load fetches the property sources of the server, the first one takes precedence.
+// The error is only returned when fail_fast is enabled.
+func (springCloudConfigSource) Load(config *ApplicationConfig) ([]PropertySource, error) {
+	cfg, err := springCloudConfig(config.current(), splitProfiles(config.GetString(EnvActiveProfiles)))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.URI == "" {
+		return nil, nil
+	}
+
+	env, err := fetchConfiguration(cfg)
+	if err != nil {
+		if cfg.FailFast {
+			return nil, err
 		}
-	}()
+		fmt.Fprintf(os.Stderr, "%v, continuing with the local configuration\n", err)
+		return nil, nil
+	}
+
+	sources := make([]PropertySource, 0, len(env.PropertySources))
+	for i := len(env.PropertySources) - 1; i >= 0; i-- {
+		ps := env.PropertySources[i]
+		sources = append(sources, PropertySource{Name: ps.Name, Values: ps.Source})
+	}
+	return sources, nil
+}
+
+// springCloudConfig reads the client settings from the configuration, its placeholders resolved, and the
+// environment variables.
+func springCloudConfig(sv *viper.Viper, profiles []string) (SpringCloudConfig, error) {
+	bindEnvs(sv, SpringCloudConfigKey, reflect.TypeOf(SpringCloudConfig{}))
 
-	resp, err := http.Get(url)
+	// AllSettings merges the environment variables of the nested keys, unlike UnmarshalKey.
+	settings := sv.AllSettings()
+	for _, p := range strings.Split(SpringCloudConfigKey, ".") {
+		settings, _ = settings[p].(map[string]interface{})
+	}
+
+	var cfg SpringCloudConfig
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &cfg,
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+	})
 	if err != nil {
-		panic("Couldn't load configuration, cannot start. Terminating. Error: " + err.Error())
+		return cfg, err
+	}
+	if err := decoder.Decode(settings); err != nil {
+		return cfg, fmt.Errorf("invalid spring cloud config client configuration: %w", err)
+	}
+	var errs ConfigErrors
+	bindValue(reflect.ValueOf(&cfg), SpringCloudConfigKey, "", func(k string) bool { return isSet(sv, k) }, &errs)
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+
+	if cfg.URI == "" {
+		cfg.URI = sv.GetString(EnvSpringCloudConfigUri)
 	}
-	body, err := ioutil.ReadAll(resp.Body)
+	if cfg.Name == "" {
+		cfg.Name = sv.GetString("spring.application.name")
+	}
+	if cfg.Name == "" {
+		cfg.Name = sv.GetString(EnvAppName)
+	}
+	if cfg.Name == "" {
+		cfg.Name = "app"
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = strings.Join(profiles, ",")
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = "default"
+	}
+	if !cfg.FailFast {
+		cfg.Retry.MaxAttempts = 1
+	}
+	return cfg, nil
+}
+
+// bindEnvs binds the environment variables of the keys of the struct type.
+func bindEnvs(v *viper.Viper, prefix string, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _ := fieldName(f)
+		key := joinKey(prefix, name)
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			bindEnvs(v, key, f.Type)
+			continue
+		}
+		_ = v.BindEnv(key)
+	}
+}
+
+// fetchConfiguration fetches the configuration from the config server, each URI is tried in order on every attempt.
+func fetchConfiguration(cfg SpringCloudConfig) (*springEnvironment, error) {
+	client, err := newSpringClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.Retry.InitialInterval
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		for _, uri := range strings.Split(cfg.URI, ",") {
+			env, err := fetchEnvironment(client, strings.TrimSpace(uri), cfg)
+			if err == nil {
+				return env, nil
+			}
+			lastErr = err
+		}
+
+		if attempt >= cfg.Retry.MaxAttempts {
+			if attempt > 1 {
+				return nil, fmt.Errorf("unable to fetch configuration from spring cloud config after %d attempts: %w", attempt, lastErr)
+			}
+			return nil, fmt.Errorf("unable to fetch configuration from spring cloud config: %w", lastErr)
+		}
+		fmt.Fprintf(os.Stderr, "unable to fetch configuration from spring cloud config: %v, retrying in %v\n", lastErr, interval)
+		time.Sleep(interval)
+		interval = time.Duration(float64(interval) * cfg.Retry.Multiplier)
+		if cfg.Retry.MaxInterval > 0 && interval > cfg.Retry.MaxInterval {
+			interval = cfg.Retry.MaxInterval
+		}
+	}
+}
+
+func fetchEnvironment(client *http.Client, uri string, cfg SpringCloudConfig) (*springEnvironment, error) {
+	u := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(uri, "/"), url.PathEscape(cfg.Name), url.PathEscape(cfg.Profile))
+	if cfg.Label != "" {
+		u += "/" + url.PathEscape(strings.ReplaceAll(cfg.Label, "/", "(_)"))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case cfg.Token != "":
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	case cfg.Username != "":
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Printf("Couldn't load configuration. Unable to parse Body. Terminating. Error: %v, %v\n", err, resp.Status)
-		return nil
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+
+	var env springEnvironment
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", u, err)
+	}
+	return &env, nil
+}
+
+func newSpringClient(cfg SpringCloudConfig) (*http.Client, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.TLS.InsecureSkipVerify}
+	if cfg.TLS.CAFile != "" {
+		pem, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read spring cloud config CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in spring cloud config CA file %s", cfg.TLS.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load spring cloud config client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
+
+// expandProperties converts the flat keys of a property source, e.g. servers[0].host, into nested settings.
+func expandProperties(source map[string]interface{}) map[string]interface{} {
+	root := make(map[string]interface{})
+	for k, value := range source {
+		path := strings.Split(strings.ReplaceAll(k, "[", ".["), ".")
+		m := root
+		for _, p := range path[:len(path)-1] {
+			child, ok := m[p].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				m[p] = child
+			}
+			m = child
+		}
+		m[path[len(path)-1]] = value
+	}
+	return indexedLists(root).(map[string]interface{})
+}
+
+// maxListSparsity bounds the length of the lists converted from indexed keys to a multiple of their items,
+// the index of a property name must not decide how much memory is allocated.
+const maxListSparsity = 2
+
+// indexedLists converts the maps with [n] keys into lists, the sparse ones are kept as maps, e.g. with a single
+// servers[1000000000] key.
+func indexedLists(value interface{}) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return value
 	}
 
-	return body
+	indexes := make(map[int]interface{}, len(m))
+	for k, v := range m {
+		m[k] = indexedLists(v)
+		if strings.HasPrefix(k, "[") && strings.HasSuffix(k, "]") {
+			if i, err := strconv.Atoi(k[1 : len(k)-1]); err == nil && i >= 0 {
+				indexes[i] = m[k]
+			}
+		}
+	}
+	if len(m) == 0 || len(indexes) != len(m) {
+		return m
+	}
+
+	keys := make([]int, 0, len(indexes))
+	for i := range indexes {
+		keys = append(keys, i)
+	}
+	sort.Ints(keys)
+	if keys[len(keys)-1] >= maxListSparsity*len(keys) {
+		return m
+	}
+	list := make([]interface{}, keys[len(keys)-1]+1)
+	for _, i := range keys {
+		list[i] = indexes[i]
+	}
+	return list
 }
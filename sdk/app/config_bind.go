@@ -0,0 +1,478 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+type (
+	// ConfigError is a problem found binding the configuration of a key.
+	ConfigError struct {
+		Key     string
+		Message string
+	}
+
+	// ConfigErrors lists every problem found binding the configuration.
+	ConfigErrors []ConfigError
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// Bind decodes the configuration under the key of the default application into a new T, see ApplicationConfig.Bind.
+func Bind[T any](key string) (T, error) {
+	return BindFrom[T](defaultApp.Config(), key)
+}
+
+// BindFrom decodes the configuration under the key into a new T, see ApplicationConfig.Bind.
+//
+//	cfg, err := app.BindFrom[grpc_server.Config](config, grpc_server.ConfigKey)
+func BindFrom[T any](c *ApplicationConfig, key string) (T, error) {
+	var v T
+	err := c.Bind(key, &v)
+	return v, err
+}
+
+// Bind decodes the configuration under the key into out, a pointer to a struct, a map or a slice.
+//
+// The fields whose key is not set are set from their default tag, then they are checked against the rules of their
+// validate tag. A key set to a zero value, e.g. timeout: 0s, keeps it:
+//
+//	Port    int           `mapstructure:"port" default:"8080" validate:"min=1,max=65535"`
+//	Timeout time.Duration `mapstructure:"timeout" validate:"required,max=1m"`
+//	Format  string        `mapstructure:"format" validate:"oneof=text json"`
+//
+// The rules are required, min and max (the value of numbers and durations, the length of strings, slices and maps),
+// oneof (space separated values), url (absolute URL) and hostport (host:port address).
+// Except for required, the rules are not checked on empty fields.
+//
+// The keys that are neither decoded into out nor into another configuration registered under the key are unknown.
+// Every problem is reported at once in a ConfigErrors, the secret values quoted by the messages are redacted.
+// The secret values are the ones of the secret keys, see IsSecretKey, and of the fields tagged secret:"true":
+//
+//	Password string `mapstructure:"password" secret:"true"`
+func (c *ApplicationConfig) Bind(key string, out interface{}) error {
+	key = strings.ToLower(key)
+
+	var errs ConfigErrors
+	unused, err := c.decode(key, out)
+	if err != nil {
+		return c.redactErrors(decodeErrors(key, err))
+	}
+
+	for _, k := range c.unknownKeys(key, reflect.TypeOf(out).Elem(), unused) {
+		errs = append(errs, ConfigError{Key: k, Message: "unknown key"})
+	}
+	v := c.current()
+	bindValue(reflect.ValueOf(out), key, "", func(k string) bool { return isSet(v, k) }, &errs)
+
+	if len(errs) > 0 {
+		return c.redactErrors(errs)
+	}
+	return nil
+}
+
+// isSet reports whether the key is set, including the keys of the list elements, e.g. servers.0.host,
+// which viper does not address.
+func isSet(v *viper.Viper, key string) bool {
+	if v.IsSet(key) {
+		return true
+	}
+	parts := strings.Split(key, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		prefix := strings.Join(parts[:i], ".")
+		if v.IsSet(prefix) {
+			return hasPath(v.Get(prefix), parts[i:])
+		}
+	}
+	return false
+}
+
+// hasPath reports whether the nested maps and lists of value hold a non nil value at the path.
+func hasPath(value interface{}, path []string) bool {
+	for _, p := range path {
+		switch current := value.(type) {
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(current) {
+				return false
+			}
+			value = current[i]
+		case map[string]interface{}:
+			value = lookupKey(current, p)
+		case map[interface{}]interface{}:
+			value = nil
+			for k, item := range current {
+				if strings.EqualFold(fmt.Sprint(k), p) {
+					value = item
+				}
+			}
+		default:
+			return false
+		}
+		if value == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupKey(m map[string]interface{}, key string) interface{} {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	for k, value := range m {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return nil
+}
+
+// redactErrors redacts the secret values quoted by the messages, see ApplicationConfig.RedactString.
+func (c *ApplicationConfig) redactErrors(errs ConfigErrors) ConfigErrors {
+	for i := range errs {
+		errs[i].Message = c.RedactString(errs[i].Message)
+	}
+	return errs
+}
+
+// ValidateConfig binds every registered configuration, see ApplicationConfig.Bind.
+// Every problem is reported at once in a ConfigErrors.
+func (a *App) ValidateConfig() error {
+	return a.config.validate()
+}
+
+// validate binds every configuration registered in the application.
+func (c *ApplicationConfig) validate() error {
+	if c.descriptors == nil {
+		return nil
+	}
+
+	var errs ConfigErrors
+	seen := make(map[ConfigError]bool)
+	for _, d := range c.descriptors() {
+		var ce ConfigErrors
+		if err := c.Bind(d.Key, d.newConfigValue()); !errors.As(err, &ce) {
+			continue
+		}
+		for _, e := range ce {
+			if !seen[e] {
+				seen[e] = true
+				errs = append(errs, e)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decode unmarshals the key into out, it returns the keys of the configuration that were not decoded.
+func (c *ApplicationConfig) decode(key string, out interface{}) (map[string]bool, error) {
+	var md mapstructure.Metadata
+	if err := c.UnmarshalKey(key, out, func(dc *mapstructure.DecoderConfig) {
+		dc.Metadata = &md
+	}); err != nil {
+		return nil, err
+	}
+
+	unused := make(map[string]bool, len(md.Unused))
+	for _, k := range md.Unused {
+		k = strings.NewReplacer("[", ".", "]", "").Replace(k)
+		unused[joinKey(key, strings.TrimPrefix(k, "."))] = true
+	}
+	return unused, nil
+}
+
+// unknownKeys returns the keys that are not decoded by any of the configurations registered under the key.
+// A key is unknown when it, or one of its parents, is unused by every configuration.
+func (c *ApplicationConfig) unknownKeys(key string, t reflect.Type, unused map[string]bool) []string {
+	sets := []map[string]bool{unused}
+	if c.descriptors != nil {
+		for _, d := range c.descriptors() {
+			if d.Key != key || reflect.TypeOf(d.Prototype) == t {
+				continue
+			}
+			if other, err := c.decode(key, d.newConfigValue()); err == nil {
+				sets = append(sets, other)
+			}
+		}
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		for k := range set {
+			if !seen[k] && unusedByAll(sets, k) {
+				keys = append(keys, k)
+			}
+			seen[k] = true
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unusedByAll(sets []map[string]bool, key string) bool {
+	for _, set := range sets {
+		unused := false
+		for k := key; k != ""; k = parentKey(k) {
+			if set[k] {
+				unused = true
+				break
+			}
+		}
+		if !unused {
+			return false
+		}
+	}
+	return true
+}
+
+func parentKey(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+func decodeErrors(key string, err error) ConfigErrors {
+	var me *mapstructure.Error
+	if !errors.As(err, &me) {
+		return ConfigErrors{{Key: key, Message: err.Error()}}
+	}
+
+	errs := make(ConfigErrors, 0, len(me.Errors))
+	for _, msg := range me.Errors {
+		errs = append(errs, ConfigError{Key: key, Message: msg})
+	}
+	return errs
+}
+
+// bindValue applies the default and validate tags of the fields of v, path is the configuration key of v.
+// The default tags apply to the fields whose key is not set.
+func bindValue(v reflect.Value, path string, rules string, set func(key string) bool, errs *ConfigErrors) {
+	if rules != "" {
+		validateValue(v, path, rules, errs)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			bindValue(v.Elem(), path, "", set, errs)
+		}
+	case reflect.Struct:
+		bindStruct(v, path, set, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			bindValue(v.Index(i), joinKey(path, strconv.Itoa(i)), "", set, errs)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(k))
+			bindValue(elem, joinKey(path, fmt.Sprint(k.Interface())), "", set, errs)
+			v.SetMapIndex(k, elem)
+		}
+	}
+}
+
+func bindStruct(v reflect.Value, path string, set func(key string) bool, errs *ConfigErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, squash := fieldName(f)
+		fieldPath := path
+		if !squash {
+			fieldPath = joinKey(path, name)
+		}
+
+		fv := v.Field(i)
+		if def, ok := f.Tag.Lookup("default"); ok && fv.IsZero() && fv.CanSet() && !set(fieldPath) {
+			if err := setString(fv, def); err != nil {
+				*errs = append(*errs, ConfigError{Key: fieldPath, Message: fmt.Sprintf("invalid default %q: %v", def, err)})
+			}
+		}
+		bindValue(fv, fieldPath, f.Tag.Get("validate"), set, errs)
+	}
+}
+
+// fieldName returns the configuration key of the field and whether it is squashed into its parent.
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("mapstructure")
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, f.Anonymous && strings.Contains(opts, "squash")
+}
+
+func validateValue(v reflect.Value, path string, rules string, errs *ConfigErrors) {
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == "required" {
+			required = true
+		}
+	}
+
+	if v.IsZero() {
+		if required {
+			*errs = append(*errs, ConfigError{Key: path, Message: "is required"})
+		}
+		return
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		var msg string
+		switch name {
+		case "", "required":
+		case "min", "max":
+			msg = checkRange(v, name, arg)
+		case "oneof":
+			msg = checkOneOf(v, path, arg)
+		case "url":
+			if u, err := url.Parse(fmt.Sprint(v.Interface())); err != nil || u.Scheme == "" {
+				msg = "must be an absolute URL"
+			}
+		case "hostport":
+			msg = checkHostPort(fmt.Sprint(v.Interface()))
+		default:
+			msg = fmt.Sprintf("unknown validation rule %s", name)
+		}
+
+		if msg != "" {
+			*errs = append(*errs, ConfigError{Key: path, Message: msg})
+		}
+	}
+}
+
+func checkRange(v reflect.Value, rule string, arg string) string {
+	var value, limit float64
+	var err error
+	verb := "be"
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		value, limit = float64(v.Int()), float64(d)
+	case v.CanInt():
+		value = float64(v.Int())
+		limit, err = strconv.ParseFloat(arg, 64)
+	case v.CanUint():
+		value = float64(v.Uint())
+		limit, err = strconv.ParseFloat(arg, 64)
+	case v.CanFloat():
+		value = v.Float()
+		limit, err = strconv.ParseFloat(arg, 64)
+	case v.Kind() == reflect.String, v.Kind() == reflect.Slice, v.Kind() == reflect.Map, v.Kind() == reflect.Array:
+		value = float64(v.Len())
+		limit, err = strconv.ParseFloat(arg, 64)
+		verb = "have a length of"
+	default:
+		return fmt.Sprintf("rule %s does not apply to %s", rule, v.Type())
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Sprintf("invalid %s rule %q: %v", rule, arg, err)
+	case rule == "min" && value < limit:
+		return fmt.Sprintf("must %s at least %s", verb, arg)
+	case rule == "max" && value > limit:
+		return fmt.Sprintf("must %s at most %s", verb, arg)
+	}
+	return ""
+}
+
+func checkOneOf(v reflect.Value, path string, arg string) string {
+	value := fmt.Sprint(v.Interface())
+	options := strings.Fields(arg)
+	for _, o := range options {
+		if strings.EqualFold(o, value) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %s, got %v", strings.Join(options, ", "), redactValue(path, value, IsSecretKey))
+}
+
+func checkHostPort(value string) string {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return "must be a host:port address"
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		return "must be a host:port address with a valid port"
+	}
+	return ""
+}
+
+// setString sets the value of a field from the text of its default tag.
+func setString(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case v.CanUint():
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		v.Set(reflect.ValueOf(strings.Split(s, ",")).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	bindTestServer struct {
+		Host string `mapstructure:"host" default:"localhost"`
+		Port int    `mapstructure:"port" default:"8080" validate:"min=1,max=65535"`
+	}
+
+	bindTestConfig struct {
+		Timeout  time.Duration    `mapstructure:"timeout" default:"30s"`
+		Enabled  bool             `mapstructure:"enabled" default:"true"`
+		Name     string           `mapstructure:"name" validate:"required"`
+		Format   string           `mapstructure:"format" validate:"oneof=text json"`
+		URL      string           `mapstructure:"url" validate:"url"`
+		Address  string           `mapstructure:"address" validate:"hostport"`
+		Retries  int              `mapstructure:"retries" validate:"max=5"`
+		Password string           `mapstructure:"password" validate:"min=12" secret:"true"`
+		Servers  []bindTestServer `mapstructure:"servers"`
+	}
+)
+
+func TestApplicationConfig_Bind(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		want     bindTestConfig
+		wantErrs []string
+	}{
+		{
+			name: "defaults apply to the keys not set",
+			yaml: `
+test:
+  name: app
+  servers:
+    - host: example.com
+    - port: 9090
+`,
+			want: bindTestConfig{
+				Timeout: 30 * time.Second,
+				Enabled: true,
+				Name:    "app",
+				Servers: []bindTestServer{{Host: "example.com", Port: 8080}, {Host: "localhost", Port: 9090}},
+			},
+		},
+		{
+			name: "zero values set explicitly are kept",
+			yaml: `
+test:
+  name: app
+  timeout: 0s
+  enabled: false
+  servers:
+    - host: ""
+      port: 0
+`,
+			// the rules, except required, are not checked on empty fields.
+			want: bindTestConfig{Name: "app", Servers: []bindTestServer{{}}},
+		},
+		{
+			name: "every rule is checked at once",
+			yaml: `
+test:
+  format: xml
+  url: /relative
+  address: localhost
+  retries: 6
+  password: short
+  unknown: 1
+`,
+			wantErrs: []string{
+				"test.unknown: unknown key",
+				"test.name: is required",
+				"test.format: must be one of text, json, got xml",
+				"test.url: must be an absolute URL",
+				"test.address: must be a host:port address",
+				"test.retries: must be at most 5",
+				"test.password: must have a length of at least 12",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseApplicationConfig([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := BindFrom[bindTestConfig](cfg, "test")
+
+			var errs ConfigErrors
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Bind() error = %v", err)
+				}
+			} else if !errors.As(err, &errs) {
+				t.Fatalf("Bind() error = %v, want ConfigErrors", err)
+			}
+			var msgs []string
+			for _, e := range errs {
+				msgs = append(msgs, e.Error())
+			}
+			if strings.Join(msgs, "\n") != strings.Join(tt.wantErrs, "\n") {
+				t.Errorf("Bind() errors =\n%s\nwant\n%s", strings.Join(msgs, "\n"), strings.Join(tt.wantErrs, "\n"))
+			}
+			if len(tt.wantErrs) == 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindFrom_shutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want time.Duration
+	}{
+		{name: "default when not set", yaml: "shutdown: {}", want: 30 * time.Second},
+		{name: "no deadline when set to zero", yaml: "shutdown:\n  timeout: 0s\n", want: 0},
+		{name: "configured", yaml: "shutdown:\n  timeout: 5s\n", want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseApplicationConfig([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadShutdownConfig(cfg)
+			if err != nil {
+				t.Fatalf("loadShutdownConfig() error = %v", err)
+			}
+			if got.Timeout != tt.want {
+				t.Errorf("Timeout = %v, want %v", got.Timeout, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// CipherPrefix marks an encrypted configuration value. The value following the prefix is the base64 of the 12 bytes
+// nonce followed by the AES-256-GCM ciphertext and its tag. Only the prefix is borrowed from spring cloud config,
+// the values encrypted by a spring cloud config server cannot be decrypted and the other way around.
+//
+//	datasource:
+//	  default:
+//	    connection_string: '{cipher}c2VjcmV0IG5vbmNl...'
+const CipherPrefix = "{cipher}"
+
+// encryptionKeySize is the size of the AES-256 key.
+const encryptionKeySize = 32
+
+// ErrNoEncryptionKey is returned when a value is encrypted or decrypted without an encryption key.
+var ErrNoEncryptionKey = errors.New("no encryption key, set the " + EnvEncryptKey + " or " + EnvEncryptKeyFile + " environment variable")
+
+// GenerateKey returns a random AES-256 key encoded in base64, to be set in ENCRYPT_KEY.
+func GenerateKey() (string, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypts the value with AES-256-GCM and returns it with the {cipher} prefix, see CipherPrefix.
+// The key is the ENCRYPT_KEY environment variable, or the content of the file in the ENCRYPT_KEY_FILE environment
+// variable: 32 bytes encoded in base64 or hex, see GenerateKey.
+func Encrypt(value string) (string, error) {
+	gcm, err := newCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return CipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt, the {cipher} prefix is optional.
+func Decrypt(value string) (string, error) {
+	gcm, err := newCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, CipherPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt value, wrong encryption key: %w", err)
+	}
+	return string(plain), nil
+}
+
+func newCipher() (cipher.AEAD, error) {
+	key, err := encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptionKey() ([]byte, error) {
+	secret := os.Getenv(EnvEncryptKey)
+	if secret == "" {
+		if path := os.Getenv(EnvEncryptKeyFile); path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read encryption key: %w", err)
+			}
+			secret = strings.TrimRight(string(b), "\r\n")
+		}
+	}
+	if secret == "" {
+		return nil, ErrNoEncryptionKey
+	}
+
+	decode := base64.StdEncoding.DecodeString
+	if len(secret) == hex.EncodedLen(encryptionKeySize) {
+		decode = hex.DecodeString
+	}
+	key, err := decode(secret)
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key, it must be %d bytes encoded in base64 or hex", encryptionKeySize)
+	}
+	return key, nil
+}
+
+// decryptValues decrypts every {cipher} value of the configuration, it returns the values that failed.
+func decryptValues(v *viper.Viper) error {
+	var failed []string
+	for _, k := range v.AllKeys() {
+		switch value := v.Get(k).(type) {
+		case string:
+			if !strings.HasPrefix(value, CipherPrefix) {
+				continue
+			}
+			plain, err := Decrypt(value)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", k, err))
+				continue
+			}
+			v.Set(k, plain)
+		case []interface{}:
+			value = append([]interface{}(nil), value...)
+			for i, item := range value {
+				s, ok := item.(string)
+				if !ok || !strings.HasPrefix(s, CipherPrefix) {
+					continue
+				}
+				plain, err := Decrypt(s)
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s.%d: %v", k, i, err))
+					continue
+				}
+				value[i] = plain
+			}
+			v.Set(k, value)
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("invalid encrypted configuration values:\n  %s", strings.Join(failed, "\n  "))
+	}
+	return nil
+}
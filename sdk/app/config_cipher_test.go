@@ -0,0 +1,175 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	testKey      = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	testOtherKey = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+)
+
+func encrypt(t *testing.T, key, value string) string {
+	t.Helper()
+	t.Setenv(EnvEncryptKey, key)
+	encrypted, err := Encrypt(value)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	return encrypted
+}
+
+// tamper flips a bit of the last byte of the encrypted value, i.e. of the authentication tag.
+func tamper(t *testing.T, encrypted string) string {
+	t.Helper()
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, CipherPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	return CipherPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+func TestDecrypt(t *testing.T) {
+	keyFile := filepath.Join(writeConfig(t, map[string]string{"key": testKey + "\n"}), "key")
+
+	tests := []struct {
+		name       string
+		key        string
+		keyFile    string
+		decryptKey string
+		value      func(encrypted string) string
+		want       string
+		wantErr    string
+	}{
+		{name: "round trip with a base64 key", key: testKey, want: "s3cr3t"},
+		{name: "round trip with a hex key", key: hex.EncodeToString([]byte("0123456789abcdef0123456789abcdef")), want: "s3cr3t"},
+		{name: "round trip with a key file", keyFile: keyFile, want: "s3cr3t"},
+		{
+			name:  "without prefix",
+			key:   testKey,
+			value: func(encrypted string) string { return strings.TrimPrefix(encrypted, CipherPrefix) },
+			want:  "s3cr3t",
+		},
+		{name: "wrong key", key: testKey, decryptKey: testOtherKey, wantErr: "wrong encryption key"},
+		{
+			name:    "tampered ciphertext",
+			key:     testKey,
+			value:   func(encrypted string) string { return tamper(t, encrypted) },
+			wantErr: "unable to decrypt value",
+		},
+		{
+			name:    "truncated value",
+			key:     testKey,
+			value:   func(string) string { return CipherPrefix + "c2hvcnQ=" },
+			wantErr: "too short",
+		},
+		{name: "key of another size", key: testKey, decryptKey: "c2hvcnQ=", wantErr: "invalid encryption key"},
+		{name: "passphrase", key: testKey, decryptKey: "my passphrase", wantErr: "invalid encryption key"},
+		{name: "no key", key: testKey, decryptKey: "-", wantErr: ErrNoEncryptionKey.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvEncryptKeyFile, tt.keyFile)
+			encrypted := encrypt(t, tt.key, "s3cr3t")
+			if !strings.HasPrefix(encrypted, CipherPrefix) {
+				t.Fatalf("Encrypt() = %q, want the %s prefix", encrypted, CipherPrefix)
+			}
+			if tt.value != nil {
+				encrypted = tt.value(encrypted)
+			}
+			switch tt.decryptKey {
+			case "":
+			case "-":
+				t.Setenv(EnvEncryptKey, "")
+			default:
+				t.Setenv(EnvEncryptKey, tt.decryptKey)
+			}
+
+			got, err := Decrypt(encrypted)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decrypt() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if got := encrypt(t, key, "s3cr3t"); !strings.HasPrefix(got, CipherPrefix) {
+		t.Errorf("Encrypt() with a generated key = %q", got)
+	}
+}
+
+func TestDecryptValues(t *testing.T) {
+	user := encrypt(t, testKey, "admin")
+	password := encrypt(t, testKey, "s3cr3t")
+	wrong := encrypt(t, testOtherKey, "other")
+	t.Setenv(EnvEncryptKey, testKey)
+
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "string values",
+			values: map[string]interface{}{"db.user": user, "db.host": "localhost"},
+			want:   map[string]interface{}{"db.user": "admin", "db.host": "localhost"},
+		},
+		{
+			name:   "list values",
+			values: map[string]interface{}{"credentials": []interface{}{user, "plain", password, 3}},
+			want:   map[string]interface{}{"credentials": []interface{}{"admin", "plain", "s3cr3t", 3}},
+		},
+		{
+			name:    "failed values",
+			values:  map[string]interface{}{"db.password": wrong, "credentials": []interface{}{user, wrong}},
+			wantErr: "credentials.1: unable to decrypt value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			for k, value := range tt.values {
+				v.Set(k, value)
+			}
+
+			err := decryptValues(v)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "db.password") {
+					t.Fatalf("decryptValues() error = %v, want %q and db.password", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decryptValues() error = %v", err)
+			}
+			for k, want := range tt.want {
+				if got := v.Get(k); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %v, want %v", k, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configCommand creates the command printing, validating and explaining the resolved configuration, printing its schema,
+// and encrypting its values.
+func (a *App) configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Configuration operations",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "print",
+				Usage:  "Print the resolved configuration, secrets are masked",
+				Action: a.printConfig,
+			},
+			{
+				Name:      "get",
+				Usage:     "Print the resolved value of a key, secrets are masked",
+				ArgsUsage: "<key>",
+				Action:    a.getConfig,
+			},
+			{
+				Name:   "validate",
+				Usage:  "Bind and validate the configuration of every registered server and component",
+				Action: a.validateConfig,
+			},
+			{
+				Name:   "schema",
+				Usage:  "Print the JSON Schema of the configuration of every registered server and component",
+				Action: a.printConfigSchema,
+			},
+			{
+				Name:   "generate-key",
+				Usage:  "Generate a random encryption key to set in the ENCRYPT_KEY",
+				Action: generateKey,
+			},
+			{
+				Name:      "encrypt",
+				Usage:     "Encrypt a value with the ENCRYPT_KEY, the value is read from the standard input when not given",
+				ArgsUsage: "[value]",
+				Action:    encryptValue,
+			},
+			{
+				Name:      "decrypt",
+				Usage:     "Decrypt a {cipher} value with the ENCRYPT_KEY, the value is read from the standard input when not given",
+				ArgsUsage: "[value]",
+				Action:    decryptValue,
+			},
+			{
+				Name:      "explain",
+				Usage:     "Show the source that produced the value of a key",
+				ArgsUsage: "<key>",
+				Action:    a.explainConfig,
+			},
+		},
+	}
+}
+
+func (a *App) printConfig(ctx *cli.Context) error {
+	out, err := yaml.Marshal(a.Config().RedactedSettings())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(ctx.App.Writer, string(out))
+	return err
+}
+
+func (a *App) getConfig(ctx *cli.Context) error {
+	key, err := keyArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg := a.Config()
+	if !cfg.IsSet(key) {
+		return fmt.Errorf("key %s is not set", key)
+	}
+
+	value := redactValue(strings.ToLower(key), cfg.Get(key), cfg.secrets())
+	if m, ok := value.(map[string]interface{}); ok {
+		out, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(ctx.App.Writer, string(out))
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.App.Writer, value)
+	return err
+}
+
+func (a *App) validateConfig(ctx *cli.Context) error {
+	if err := a.ValidateConfig(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(ctx.App.Writer, "configuration is valid")
+	return err
+}
+
+func (a *App) printConfigSchema(ctx *cli.Context) error {
+	enc := json.NewEncoder(ctx.App.Writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.ConfigSchema())
+}
+
+func (a *App) explainConfig(ctx *cli.Context) error {
+	key, err := keyArg(ctx)
+	if err != nil {
+		return err
+	}
+	key = strings.ToLower(key)
+
+	cfg := a.Config()
+	source := cfg.Source(key)
+	if source == "" {
+		return fmt.Errorf("key %s is not set", key)
+	}
+
+	w := ctx.App.Writer
+	_, _ = fmt.Fprintf(w, "key:    %s\n", key)
+	_, _ = fmt.Fprintf(w, "value:  %v\n", redactValue(key, cfg.Get(key), cfg.secrets()))
+	_, _ = fmt.Fprintf(w, "source: %s\n", source)
+	if raw := cfg.RawValue(key); raw != nil && fmt.Sprint(raw) != fmt.Sprint(cfg.Get(key)) {
+		_, _ = fmt.Fprintf(w, "raw:    %v\n", redactValue(key, raw, cfg.secrets()))
+	}
+	return nil
+}
+
+func generateKey(ctx *cli.Context) error {
+	key, err := GenerateKey()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.App.Writer, key)
+	return err
+}
+
+func encryptValue(ctx *cli.Context) error {
+	value, err := valueArg(ctx)
+	if err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.App.Writer, encrypted)
+	return err
+}
+
+func decryptValue(ctx *cli.Context) error {
+	value, err := valueArg(ctx)
+	if err != nil {
+		return err
+	}
+	decrypted, err := Decrypt(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.App.Writer, decrypted)
+	return err
+}
+
+// valueArg returns the value argument, or the standard input without its trailing new line,
+// so the value is not kept in the shell history.
+func valueArg(ctx *cli.Context) (string, error) {
+	switch ctx.NArg() {
+	case 0:
+		b, err := io.ReadAll(ctx.App.Reader)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case 1:
+		return ctx.Args().First(), nil
+	}
+	return "", errors.New("expected at most one value argument")
+}
+
+func keyArg(ctx *cli.Context) (string, error) {
+	if ctx.NArg() != 1 {
+		return "", errors.New("expected exactly one key argument")
+	}
+	return ctx.Args().First(), nil
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// configServer is a spring cloud config server answering with the statuses in order, the last one is repeated.
+type configServer struct {
+	*httptest.Server
+	statuses []int
+	env      springEnvironment
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newConfigServer(t *testing.T, env springEnvironment, statuses ...int) *configServer {
+	t.Helper()
+	s := &configServer{statuses: statuses, env: env}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		status := http.StatusOK
+		if n := len(s.statuses); n > 0 && len(s.requests) > n {
+			status = s.statuses[n-1]
+		} else if n > 0 {
+			status = s.statuses[len(s.requests)-1]
+		}
+		s.mu.Unlock()
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.env)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *configServer) received() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func TestSpringCloudConfigSource_Load(t *testing.T) {
+	env := springEnvironment{
+		Name:     "item",
+		Profiles: []string{"dev"},
+		PropertySources: []springPropertySource{
+			{Name: "item-dev.yaml", Source: map[string]interface{}{"greeting": "dev"}},
+			{Name: "item.yaml", Source: map[string]interface{}{"greeting": "default", "other": "x"}},
+		},
+	}
+	const retry = `
+      fail_fast: true
+      retry:
+        max_attempts: 3
+        initial_interval: 1ms
+        max_interval: 2ms
+`
+
+	tests := []struct {
+		name string
+		// config is the client configuration, %[1]s is the URI of the server and %[2]s the URI of an unreachable one.
+		config       string
+		statuses     []int
+		wantErr      string
+		wantRequests int
+		wantPath     string
+		wantAuth     string
+		wantSources  []string
+	}{
+		{
+			name:         "property sources by increasing precedence",
+			config:       "uri: %[1]s\n      name: item\n      profile: dev\n      label: feature/x",
+			wantRequests: 1,
+			wantPath:     "/item/dev/feature(_)x",
+			wantSources:  []string{"item.yaml", "item-dev.yaml"},
+		},
+		{
+			name:         "basic authentication",
+			config:       "uri: %[1]s\n      username: user\n      password: secret",
+			wantRequests: 1,
+			wantPath:     "/app/default",
+			wantAuth:     "Basic dXNlcjpzZWNyZXQ=",
+			wantSources:  []string{"item.yaml", "item-dev.yaml"},
+		},
+		{
+			name:         "bearer authentication over basic",
+			config:       "uri: %[1]s\n      username: user\n      token: t0k3n",
+			wantRequests: 1,
+			wantAuth:     "Bearer t0k3n",
+			wantSources:  []string{"item.yaml", "item-dev.yaml"},
+		},
+		{
+			name:         "error status ignored without fail_fast",
+			config:       "uri: %[1]s",
+			statuses:     []int{http.StatusInternalServerError},
+			wantRequests: 1,
+		},
+		{
+			name:         "unreachable server ignored without fail_fast",
+			config:       "uri: %[2]s",
+			wantRequests: 0,
+		},
+		{
+			name:         "error status retried with fail_fast",
+			config:       "uri: %[1]s" + retry,
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantErr:      "after 3 attempts",
+			wantRequests: 3,
+		},
+		{
+			name:         "recovered after retries",
+			config:       "uri: %[1]s" + retry,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			wantRequests: 3,
+			wantSources:  []string{"item.yaml", "item-dev.yaml"},
+		},
+		{
+			name:         "fallback to the next uri",
+			config:       "uri: %[2]s, %[1]s" + retry,
+			wantRequests: 1,
+			wantSources:  []string{"item.yaml", "item-dev.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newConfigServer(t, env, tt.statuses...)
+			unreachable := httptest.NewServer(http.NotFoundHandler())
+			unreachable.Close()
+
+			yaml := "spring:\n  cloud:\n    config:\n      " + fmt.Sprintf(tt.config, server.URL, unreachable.URL) + "\n"
+			cfg, err := ParseApplicationConfig([]byte(yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sources, err := springCloudConfigSource{}.Load(cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			requests := server.received()
+			if len(requests) != tt.wantRequests {
+				t.Fatalf("server requests = %d, want %d", len(requests), tt.wantRequests)
+			}
+			if tt.wantPath != "" && requests[0].URL.Path != tt.wantPath {
+				t.Errorf("request path = %q, want %q", requests[0].URL.Path, tt.wantPath)
+			}
+			if len(requests) > 0 && requests[0].Header.Get("Authorization") != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", requests[0].Header.Get("Authorization"), tt.wantAuth)
+			}
+
+			var names []string
+			for _, ps := range sources {
+				names = append(names, ps.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantSources) {
+				t.Errorf("Load() sources = %v, want %v", names, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestApp_LoadConfig_springCloudConfig(t *testing.T) {
+	server := newConfigServer(t, springEnvironment{
+		PropertySources: []springPropertySource{
+			{Name: "item-dev.yaml", Source: map[string]interface{}{"greeting": "dev"}},
+			{Name: "item.yaml", Source: map[string]interface{}{
+				"greeting":        "default",
+				"local":           "remote",
+				"servers[0].host": "a",
+				"servers[1].host": "b",
+				"sparse[1000000]": "x",
+			}},
+		},
+	})
+	dir := writeConfig(t, map[string]string{
+		"application.yaml": fmt.Sprintf("spring:\n  cloud:\n    config:\n      uri: %s\nlocal: file\nonly_local: file\n", server.URL),
+	})
+
+	a := New()
+	if err := a.LoadConfig(dir); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg := a.Config()
+
+	for key, want := range map[string]string{"greeting": "dev", "local": "remote", "only_local": "file"} {
+		if got := cfg.GetString(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	wantServers := []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"host": "b"}}
+	if got := cfg.Get("servers"); !reflect.DeepEqual(got, wantServers) {
+		t.Errorf("servers = %v, want %v", got, wantServers)
+	}
+	if got, ok := cfg.Get("sparse").(map[string]interface{}); !ok || got["[1000000]"] != "x" {
+		t.Errorf("sparse = %v, want a map keeping the [1000000] index", cfg.Get("sparse"))
+	}
+}
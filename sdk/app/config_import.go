@@ -0,0 +1,315 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportConfigKey is the configuration key of the additional configuration files and trees to load.
+//
+//	config:
+//	  import:
+//	    - shared.json                       # relative to the importing file, it fails when missing
+//	    - optional:local.properties         # ignored when missing
+//	    - optional:configtree:/etc/config/  # each file of the directory is a key
+//
+// The imported values take precedence over the ones of the importing file, and the imported files may import
+// other files. The yaml, json, toml, properties and env formats are detected from the file extension.
+const ImportConfigKey = "config.import"
+
+const (
+	optionalImportPrefix   = "optional:"
+	configTreeImportPrefix = "configtree:"
+)
+
+type (
+	// configLoader merges the configuration files into v, recording the origin of their keys.
+	configLoader struct {
+		v       *viper.Viper
+		origins map[string]string
+		// seen holds the absolute path of the merged files, a file is imported once.
+		seen map[string]bool
+		errs []error
+		// profiles are the active profiles, the activated documents are deferred until they are known.
+		profiles []string
+		active   bool
+		deferred []configDocument
+	}
+
+	// configDocument is a document of a configuration file, a yaml file may hold several.
+	configDocument struct {
+		file   string
+		values *viper.Viper
+	}
+)
+
+// mergeConfigFile merges the first file with the given name found in the paths, recording the file as the origin
+// of its keys, then the files it imports. The format is detected from the file extension, e.g. application.json.
+func (l *configLoader) mergeConfigFile(name string, paths []string) error {
+	fv := viper.New()
+	fv.SetConfigName(name)
+	for _, p := range paths {
+		fv.AddConfigPath(p)
+	}
+	if err := fv.ReadInConfig(); err != nil {
+		return err
+	}
+
+	file := fv.ConfigFileUsed()
+	if abs, err := filepath.Abs(file); err == nil {
+		l.seen[abs] = true
+	}
+	docs, err := configDocuments(file, fv)
+	if err != nil {
+		return err
+	}
+	l.mergeDocuments(docs)
+	return nil
+}
+
+// mergeDocuments merges the documents in order, the activated ones are deferred until the profiles are known.
+func (l *configLoader) mergeDocuments(docs []configDocument) {
+	for _, doc := range docs {
+		if doc.values.GetString(ProfileActivationKey) == "" {
+			l.mergeDocument(doc)
+			continue
+		}
+		if !l.active {
+			l.deferred = append(l.deferred, doc)
+			continue
+		}
+		l.mergeActivated(doc)
+	}
+}
+
+// activate sets the active profiles and merges the deferred documents they activate.
+func (l *configLoader) activate(profiles []string) {
+	l.profiles = profiles
+	l.active = true
+
+	deferred := l.deferred
+	l.deferred = nil
+	for _, doc := range deferred {
+		l.mergeActivated(doc)
+	}
+}
+
+func (l *configLoader) mergeActivated(doc configDocument) {
+	expr := doc.values.GetString(ProfileActivationKey)
+	ok, err := matchProfiles(expr, l.profiles)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %s: %w", doc.file, ProfileActivationKey, err))
+		return
+	}
+	if ok {
+		l.mergeDocument(doc)
+	}
+}
+
+// mergeDocument merges the values of the document, without its activation, then the files it imports.
+func (l *configLoader) mergeDocument(doc configDocument) {
+	settings := doc.values.AllSettings()
+	deleteKey(settings, profileActivationParent)
+	if err := l.v.MergeConfigMap(settings); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %w", doc.file, err))
+		return
+	}
+	recordOrigins(l.origins, "", settings, doc.file)
+
+	l.importConfig(doc.file, stringList(doc.values.Get(ImportConfigKey)))
+}
+
+// importConfig merges the imported locations in order, the paths are relative to the directory of the base file.
+// The errors of the required locations, and the errors other than a missing file, are reported.
+func (l *configLoader) importConfig(base string, locations []string) {
+	for _, location := range locations {
+		path := strings.TrimPrefix(location, optionalImportPrefix)
+		optional := path != location
+
+		var err error
+		if dir := strings.TrimPrefix(path, configTreeImportPrefix); dir != path {
+			err = mergeConfigTree(l.v, importPath(base, dir), l.origins)
+		} else {
+			err = l.importFile(importPath(base, path))
+		}
+
+		if err != nil && !(optional && errors.Is(err, fs.ErrNotExist)) {
+			l.errs = append(l.errs, fmt.Errorf("%s: %w", location, err))
+		}
+	}
+}
+
+// importFile merges the documents of the file, a file is imported once.
+func (l *configLoader) importFile(path string) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		if l.seen[abs] {
+			return nil
+		}
+		l.seen[abs] = true
+	}
+
+	fv := viper.New()
+	fv.SetConfigFile(path)
+	if err := fv.ReadInConfig(); err != nil {
+		return err
+	}
+	docs, err := configDocuments(path, fv)
+	if err != nil {
+		return err
+	}
+	l.mergeDocuments(docs)
+	return nil
+}
+
+// configDocuments returns the documents of the file read in fv, the documents of a yaml file are separated by ---.
+func configDocuments(file string, fv *viper.Viper) ([]configDocument, error) {
+	ext := filepath.Ext(file)
+	if ext != ".yaml" && ext != ".yml" {
+		return []configDocument{{file: file, values: fv}}, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []configDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		out, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		dv := viper.New()
+		dv.SetConfigType("yaml")
+		if err := dv.ReadConfig(bytes.NewReader(out)); err != nil {
+			return nil, err
+		}
+		docs = append(docs, configDocument{file: file, values: dv})
+	}
+	return docs, nil
+}
+
+// stringList returns the items of a list or of a comma separated string.
+func stringList(value interface{}) []string {
+	var items []string
+	switch value := value.(type) {
+	case string:
+		items = strings.Split(value, ",")
+	case []interface{}:
+		for _, item := range value {
+			items = append(items, fmt.Sprint(item))
+		}
+	case []string:
+		items = value
+	}
+
+	var trimmed []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
+
+func importPath(base, path string) string {
+	if filepath.IsAbs(path) || base == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(base), path)
+}
+
+// mergeConfigTree merges a directory where each file is a key and its content the value, as the Kubernetes
+// ConfigMap and Secret volumes. The dots of the file names and the sub directories nest the keys,
+// e.g. datasource.default.connection_string or datasource/default/connection_string.
+// Hidden files are ignored, including the ..data directory of the volumes.
+func mergeConfigTree(v *viper.Viper, dir string, origins map[string]string) error {
+	settings := make(map[string]interface{})
+	if err := readConfigTree(dir, "", settings, origins); err != nil {
+		return err
+	}
+	return v.MergeConfigMap(settings)
+}
+
+func readConfigTree(dir, prefix string, settings map[string]interface{}, origins map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		key := joinKey(prefix, strings.ToLower(e.Name()))
+
+		// the files of the volumes are symbolic links, Stat follows them.
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := readConfigTree(path, key, settings, origins); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		setNestedValue(settings, key, strings.TrimRight(string(b), "\r\n"))
+		origins[key] = path
+	}
+	return nil
+}
+
+// setNestedValue sets the value of the dotted key in the nested maps of settings.
+func setNestedValue(settings map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := settings[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			settings[p] = child
+		}
+		settings = child
+	}
+	settings[parts[len(parts)-1]] = value
+}
+
+// deleteKey deletes the dotted key from the nested maps of settings, and the maps it leaves empty.
+func deleteKey(settings map[string]interface{}, key string) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) == 1 {
+		delete(settings, key)
+		return
+	}
+	child, ok := settings[parts[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteKey(child, parts[1])
+	if len(child) == 0 {
+		delete(settings, parts[0])
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApp_LoadConfig_imports(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "imported values over the importing file",
+			files: map[string]string{
+				"application.yaml": "config:\n  import:\n    - shared.json\nname: app\nport: 1\n",
+				"shared.json":      `{"port": 2}`,
+			},
+			want: map[string]string{"name": "app", "port": "2"},
+		},
+		{
+			name: "formats detected from the extension",
+			files: map[string]string{
+				"application.yaml": "config:\n  import: [a.properties, b.toml, c.env]\n",
+				"a.properties":     "db.host=from properties\n",
+				"b.toml":           "[db]\nuser = \"from toml\"\n",
+				"c.env":            "GREETING=from env\n",
+			},
+			want: map[string]string{"db.host": "from properties", "db.user": "from toml", "greeting": "from env"},
+		},
+		{
+			name: "imports relative to the importing file",
+			files: map[string]string{
+				"application.yaml":     "config:\n  import: [shared/a.yaml]\nvalue: application\n",
+				"shared/a.yaml":        "config:\n  import: [nested/b.yaml]\nvalue: a\n",
+				"shared/nested/b.yaml": "value: b\n",
+			},
+			want: map[string]string{"value": "b"},
+		},
+		{
+			name: "files imported once",
+			files: map[string]string{
+				"application.yaml": "config:\n  import: [a.yaml]\nvalue: application\n",
+				"a.yaml":           "config:\n  import: [application.yaml]\nvalue: a\n",
+			},
+			want: map[string]string{"value": "a"},
+		},
+		{
+			name: "optional import missing",
+			files: map[string]string{
+				"application.yaml": "config:\n  import: [optional:missing.yaml]\nname: app\n",
+			},
+			want: map[string]string{"name": "app"},
+		},
+		{
+			name: "required import missing",
+			files: map[string]string{
+				"application.yaml": "config:\n  import: [missing.yaml]\nname: app\n",
+			},
+			wantErr: "missing.yaml",
+		},
+		{
+			name: "config tree",
+			files: map[string]string{
+				"application.yaml":             "config:\n  import: [configtree:tree/]\n",
+				"tree/db.password":             "secret\n",
+				"tree/datasource/default/user": "admin",
+			},
+			want: map[string]string{"db.password": "secret", "datasource.default.user": "admin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfig(t, tt.files)
+			a := New()
+
+			err := a.LoadConfig(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			for key, want := range tt.want {
+				if got := a.Config().GetString(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
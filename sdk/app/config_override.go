@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type (
+	// keyValues is a repeatable key=value command line flag, e.g. --set grpc.port=9090 --set admin.port=9091.
+	keyValues []string
+
+	// commandLineValue is a value given on the command line, see commandLineOverrides.
+	commandLineValue struct {
+		value  string
+		origin string
+	}
+)
+
+func (k *keyValues) Set(value string) error {
+	if key, _, ok := strings.Cut(value, "="); !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	*k = append(*k, value)
+	return nil
+}
+
+func (k *keyValues) String() string {
+	return strings.Join(*k, ", ")
+}
+
+// commandLineOverrides returns the values of the --set flags and the content of the files of the --set-file flags,
+// by key. The trailing new line of the files is removed.
+func commandLineOverrides(sets, files []string) (map[string]commandLineValue, error) {
+	overrides := make(map[string]commandLineValue)
+	for _, set := range sets {
+		key, value, _ := strings.Cut(set, "=")
+		overrides[strings.ToLower(strings.TrimSpace(key))] = commandLineValue{value: value, origin: "command line --" + FlagSet}
+	}
+	for _, set := range files {
+		key, path, _ := strings.Cut(set, "=")
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %s: %w", FlagSetFile, set, err)
+		}
+		overrides[strings.ToLower(strings.TrimSpace(key))] = commandLineValue{
+			value:  strings.TrimRight(string(b), "\r\n"),
+			origin: fmt.Sprintf("command line --%s %s", FlagSetFile, path),
+		}
+	}
+	return overrides, nil
+}
+
+// setCommandLine sets the values given on the command line, applied by the next load. The values are applied at once
+// to a configuration already loaded, e.g. given WithConfig.
+func (c *ApplicationConfig) setCommandLine(overrides map[string]commandLineValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commandLine = overrides
+	if !c.loaded {
+		return
+	}
+	for key, value := range overrides {
+		c.viper.Set(key, value.value)
+		c.origins[key] = value.origin
+		c.raw[key] = value.value
+		c.pinned[key] = true
+	}
+}
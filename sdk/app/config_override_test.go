@@ -0,0 +1,101 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommandLineOverrides(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"application.yaml": "greeting: from file\n",
+		"greeting.txt":     "from set-file\n",
+	})
+
+	tests := []struct {
+		name       string
+		sets       []string
+		files      []string
+		env        string
+		sources    []ConfigSource
+		want       string
+		wantSource string
+	}{
+		{
+			name:       "set over the files",
+			sets:       []string{"greeting=from set"},
+			want:       "from set",
+			wantSource: "command line --set",
+		},
+		{
+			name:       "set over the environment variables",
+			sets:       []string{"greeting=from set"},
+			env:        "from env",
+			want:       "from set",
+			wantSource: "command line --set",
+		},
+		{
+			name: "set over the sources of a higher priority than the environment variables",
+			sets: []string{"GREETING=from set"},
+			sources: []ConfigSource{staticSource{
+				name:     "vault",
+				priority: 200,
+				values:   map[string]interface{}{"greeting": "from vault"},
+			}},
+			want:       "from set",
+			wantSource: "command line --set",
+		},
+		{
+			name:  "set-file over the sources",
+			files: []string{"greeting=" + filepath.Join(dir, "greeting.txt")},
+			sources: []ConfigSource{staticSource{
+				name:     "kv",
+				priority: ConfigPrioritySpringCloudConfig,
+				values:   map[string]interface{}{"greeting": "from kv"},
+			}},
+			want:       "from set-file",
+			wantSource: "command line --set-file " + filepath.Join(dir, "greeting.txt"),
+		},
+		{
+			name: "sources of a higher priority over the environment variables without set",
+			env:  "from env",
+			sources: []ConfigSource{staticSource{
+				name:     "vault",
+				priority: 200,
+				values:   map[string]interface{}{"greeting": "from vault"},
+			}},
+			want:       "from vault",
+			wantSource: "vault values",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("GREETING", tt.env)
+			} else if _, ok := os.LookupEnv("GREETING"); ok {
+				t.Skip("GREETING is set in the environment")
+			}
+
+			a := New()
+			for _, s := range tt.sources {
+				a.RegisterConfigSource(s)
+			}
+			overrides, err := commandLineOverrides(tt.sets, tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.Config().setCommandLine(overrides)
+			if err := a.LoadConfig(dir); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			cfg := a.Config()
+			if got := cfg.GetString("greeting"); got != tt.want {
+				t.Errorf("greeting = %q, want %q", got, tt.want)
+			}
+			if got := cfg.Source("greeting"); got != tt.wantSource {
+				t.Errorf("Source(greeting) = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
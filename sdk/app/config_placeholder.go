@@ -0,0 +1,358 @@
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// PlaceholderResolver resolves the argument of a ${prefix://argument} placeholder.
+type PlaceholderResolver func(argument string) (string, error)
+
+// resolverSeparator separates the prefix of a resolver placeholder from its argument. It does not collide with
+// the ${KEY:default} placeholders, a key is not followed by //.
+const resolverSeparator = "://"
+
+// builtinResolvers returns the resolvers every application starts with.
+func builtinResolvers() map[string]PlaceholderResolver {
+	return map[string]PlaceholderResolver{
+		"file":   resolveFile,
+		"env":    resolveEnv,
+		"base64": resolveBase64,
+	}
+}
+
+// RegisterPlaceholderResolver registers the resolver of the ${prefix://argument} placeholders in the default application.
+// See App.RegisterPlaceholderResolver.
+func RegisterPlaceholderResolver(prefix string, resolver PlaceholderResolver) {
+	defaultApp.RegisterPlaceholderResolver(prefix, resolver)
+}
+
+// RegisterPlaceholderResolver registers the resolver of the ${prefix://argument} placeholders, e.g. to read the secrets
+// of a vault. It replaces the resolver previously registered with the prefix. The built-in resolvers are:
+//
+//	${file:///run/secrets/db_password} # the content of the file, without the trailing new line
+//	${env://DB_PASSWORD}               # the environment variable, it fails when the variable is not set
+//	${base64://cGFzc3dvcmQ=}           # the decoded value
+//
+// The configuration fails to load when a resolver returns an error, or when no resolver is registered with the
+// prefix of a placeholder.
+func (a *App) RegisterPlaceholderResolver(prefix string, resolver PlaceholderResolver) {
+	a.resolversMu.Lock()
+	defer a.resolversMu.Unlock()
+	a.resolvers[prefix] = resolver
+}
+
+func (a *App) placeholderResolver(prefix string) (PlaceholderResolver, bool) {
+	a.resolversMu.RLock()
+	defer a.resolversMu.RUnlock()
+	r, ok := a.resolvers[prefix]
+	return r, ok
+}
+
+// placeholderResolvers returns the resolvers of the application of the configuration, the ones of the default
+// application for a configuration created without application, e.g. with LoadApplicationConfig.
+func (c *ApplicationConfig) placeholderResolvers() resolverLookup {
+	if c.resolvers != nil {
+		return c.resolvers
+	}
+	return defaultPlaceholderResolver
+}
+
+func defaultPlaceholderResolver(prefix string) (PlaceholderResolver, bool) {
+	return defaultApp.placeholderResolver(prefix)
+}
+
+func resolveFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func resolveEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func resolveBase64(encoded string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type (
+	// placeholders resolves the placeholders of the configuration values:
+	//
+	//	${KEY}                   # the value of the configuration key or environment variable, empty when not set
+	//	${KEY:default}           # the default when the key is not set or empty
+	//	${grpc.host}             # another configuration key, its own placeholders are resolved
+	//	${A:${B:default}}        # nested placeholders, in the defaults and the keys
+	//	${prefix://argument}     # the registered resolver, see RegisterPlaceholderResolver
+	//	\${literal}              # an escaped placeholder, resolved to ${literal}
+	//
+	// A value made of a single placeholder keeps the type of the value it references.
+	placeholders struct {
+		v         *viper.Viper
+		resolvers resolverLookup
+		resolved  map[string]interface{}
+	}
+
+	// resolverLookup returns the resolver registered with the prefix.
+	resolverLookup func(prefix string) (PlaceholderResolver, bool)
+
+	// placeholderCycleError reports keys referencing each other.
+	placeholderCycleError struct {
+		keys []string
+	}
+)
+
+func (e *placeholderCycleError) Error() string {
+	return fmt.Sprintf("placeholder cycle %s", strings.Join(e.keys, " -> "))
+}
+
+// resolvePlaceholders resolves the placeholders of every key, it returns the keys that failed.
+func resolvePlaceholders(v *viper.Viper, resolvers resolverLookup) error {
+	p := &placeholders{v: v, resolvers: resolvers, resolved: make(map[string]interface{})}
+
+	var failed []string
+	values := make(map[string]interface{})
+	for _, k := range v.AllKeys() {
+		value, err := p.key(k, nil)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", k, err))
+			continue
+		}
+		values[k] = value
+	}
+	// the values are set once resolved, the references read the values before their resolution.
+	for k, value := range values {
+		v.Set(k, value)
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("invalid configuration placeholders:\n  %s", strings.Join(failed, "\n  "))
+	}
+	return nil
+}
+
+// key resolves the value of the key, stack holds the keys being resolved that reference it.
+func (p *placeholders) key(key string, stack []string) (interface{}, error) {
+	key = strings.ToLower(key)
+	for i, k := range stack {
+		if k == key {
+			return nil, &placeholderCycleError{keys: append(append([]string(nil), stack[i:]...), key)}
+		}
+	}
+	if value, ok := p.resolved[key]; ok {
+		return value, nil
+	}
+
+	value, err := p.value(p.v.Get(key), append(stack, key))
+	if err != nil {
+		return nil, err
+	}
+	p.resolved[key] = value
+	return value, nil
+}
+
+// value resolves the placeholders of the strings of the value, the lists and maps are copied.
+func (p *placeholders) value(value interface{}, stack []string) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return p.string(value, stack)
+	case []interface{}:
+		resolved := make([]interface{}, len(value))
+		for i, item := range value {
+			r, err := p.value(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			r, err := p.value(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = r
+		}
+		return resolved, nil
+	case map[interface{}]interface{}:
+		resolved := make(map[interface{}]interface{}, len(value))
+		for k, item := range value {
+			r, err := p.value(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = r
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
+
+// string resolves the placeholders of s, a string made of a single placeholder returns the referenced value.
+func (p *placeholders) string(s string, stack []string) (interface{}, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], `\${`) {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			// an unterminated placeholder is kept as it is.
+			b.WriteString(s[i:])
+			break
+		}
+		value, err := p.placeholder(s[i:end+1], s[i+2:end], stack)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && end == len(s)-1 {
+			return value, nil
+		}
+		if value != nil {
+			b.WriteString(fmt.Sprint(value))
+		}
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// placeholder resolves the content of the ${content} placeholder.
+func (p *placeholders) placeholder(match, content string, stack []string) (interface{}, error) {
+	if prefix, argument, ok := resolverPlaceholder(content); ok {
+		resolver, ok := p.resolvers(prefix)
+		if !ok {
+			return nil, fmt.Errorf("unable to resolve %s: no placeholder resolver registered with the prefix %s", match, prefix)
+		}
+		argument, err := p.stringValue(argument, stack)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := resolver(argument)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve %s: %w", match, err)
+		}
+		return resolved, nil
+	}
+
+	name, def, hasDefault := content, "", false
+	if i := separator(content); i >= 0 {
+		name, def, hasDefault = content[:i], content[i+1:], true
+	}
+
+	key, err := p.stringValue(name, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.key(key, stack)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil || value == "" {
+		if !hasDefault {
+			return "", nil
+		}
+		return p.string(def, stack)
+	}
+	if s, ok := value.(string); ok && strings.HasPrefix(s, CipherPrefix) {
+		decrypted, err := Decrypt(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt %s: %w", match, err)
+		}
+		return decrypted, nil
+	}
+	return value, nil
+}
+
+func (p *placeholders) stringValue(s string, stack []string) (string, error) {
+	value, err := p.string(s, stack)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// resolverPlaceholder splits the content of a ${prefix://argument} placeholder. The prefix is made of letters,
+// digits, -, _ and ., starting with a letter.
+func resolverPlaceholder(content string) (prefix, argument string, ok bool) {
+	i := strings.Index(content, resolverSeparator)
+	if i <= 0 {
+		return "", "", false
+	}
+	prefix = content[:i]
+	for j, r := range prefix {
+		letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		if !letter && (j == 0 || !(r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')) {
+			return "", "", false
+		}
+	}
+	return prefix, content[i+len(resolverSeparator):], true
+}
+
+// closingBrace returns the index of the brace closing the placeholder starting before start, or -1.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], `\${`):
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// separator returns the index of the colon separating the key of a placeholder from its default, or -1.
+// The colons of nested placeholders are ignored.
+func separator(content string) int {
+	depth := 0
+	for i := 0; i < len(content); i++ {
+		switch {
+		case strings.HasPrefix(content[i:], "${"):
+			depth++
+			i++
+		case content[i] == '}':
+			depth--
+		case content[i] == ':' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
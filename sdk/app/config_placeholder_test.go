@@ -0,0 +1,179 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// writeConfig writes the files in a temporary directory and returns it.
+func writeConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestApp_RegisterPlaceholderResolver(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"application.yaml": "password: ${vault://db}\n"})
+
+	withVault := New()
+	withVault.RegisterPlaceholderResolver("vault", func(argument string) (string, error) {
+		return "secret-of-" + argument, nil
+	})
+	without := New()
+
+	tests := []struct {
+		name    string
+		app     *App
+		want    string
+		wantErr string
+	}{
+		{name: "resolved by the resolver of the application", app: withVault, want: "secret-of-db"},
+		{
+			name:    "not resolved by the resolvers of another application",
+			app:     without,
+			wantErr: "password: unable to resolve ${vault://db}: no placeholder resolver registered with the prefix vault",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app.LoadConfig(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := tt.app.Config().GetString("password"); got != tt.want {
+				t.Errorf("password = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePlaceholders(t *testing.T) {
+	t.Setenv("WIZAPP_TEST_USER", "admin")
+
+	tests := []struct {
+		name    string
+		yaml    string
+		key     string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "references another key",
+			yaml: "db:\n  host: localhost\nurl: postgres://${db.host}:5432\n",
+			key:  "url",
+			want: "postgres://localhost:5432",
+		},
+		{
+			name: "references a key referencing another one",
+			yaml: "host: localhost\naddr: ${host}:5432\nurl: postgres://${addr}\n",
+			key:  "url",
+			want: "postgres://localhost:5432",
+		},
+		{
+			name: "nested defaults",
+			yaml: "url: ${WIZAPP_TEST_MISSING_A:${WIZAPP_TEST_MISSING_B:fallback}}\n",
+			key:  "url",
+			want: "fallback",
+		},
+		{
+			name: "nested key",
+			yaml: "env: dev\nhosts:\n  dev: dev.local\nhost: ${hosts.${env}}\n",
+			key:  "host",
+			want: "dev.local",
+		},
+		{
+			name: "escaped placeholder",
+			yaml: "literal: \\${db.host}\ndb:\n  host: localhost\n",
+			key:  "literal",
+			want: "${db.host}",
+		},
+		{
+			name: "single placeholder keeps the type",
+			yaml: "port: 5432\nref: ${port}\n",
+			key:  "ref",
+			want: 5432,
+		},
+		{
+			name: "resolver prefix",
+			yaml: "user: ${env://WIZAPP_TEST_USER}\n",
+			key:  "user",
+			want: "admin",
+		},
+		{
+			name: "resolver argument with placeholders",
+			yaml: "name: WIZAPP_TEST_USER\nuser: ${env://${name}}\n",
+			key:  "user",
+			want: "admin",
+		},
+		{
+			name: "key named as a resolver",
+			yaml: "env: prod\nprofile: ${env:dev}\n",
+			key:  "profile",
+			want: "prod",
+		},
+		{
+			name: "default holding a url",
+			yaml: "url: ${WIZAPP_TEST_MISSING:http://localhost:8080}\n",
+			key:  "url",
+			want: "http://localhost:8080",
+		},
+		{
+			name:    "unregistered resolver prefix",
+			yaml:    "password: ${vault://db}\n",
+			wantErr: "password: unable to resolve ${vault://db}: no placeholder resolver registered with the prefix vault",
+		},
+		{
+			name:    "cycle between keys",
+			yaml:    "a: ${b}\nb: x${a}\n",
+			wantErr: "a: placeholder cycle a -> b -> a",
+		},
+		{
+			name:    "cycle through a default",
+			yaml:    "a: ${WIZAPP_TEST_MISSING:${a}}\n",
+			wantErr: "a: placeholder cycle a -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.SetConfigType("yaml")
+			if err := v.ReadConfig(strings.NewReader(tt.yaml)); err != nil {
+				t.Fatalf("unable to read configuration: %v", err)
+			}
+			v.AutomaticEnv()
+
+			err := resolvePlaceholders(v, defaultPlaceholderResolver)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolvePlaceholders() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePlaceholders() error = %v", err)
+			}
+			if got := v.Get(tt.key); got != tt.want {
+				t.Errorf("%s = %v (%T), want %v (%T)", tt.key, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
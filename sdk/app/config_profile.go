@@ -0,0 +1,199 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ProfileActivationKey is the configuration key of the profile expression activating a document of a yaml file,
+	// the documents are separated by ---. The expression combines profiles with | (or), & (and), ! (not) and
+	// parentheses, a comma is an or.
+	//
+	//	grpc:
+	//	  port: 8080
+	//	---
+	//	config:
+	//	  activate:
+	//	    on-profile: dev | test
+	//	grpc:
+	//	  port: 9090
+	//	---
+	//	config:
+	//	  activate:
+	//	    on-profile: "!prod"
+	//	logger:
+	//	  level: debug
+	//
+	// The activated documents take precedence over the documents without activation of the file, in order.
+	ProfileActivationKey = "config.activate.on-profile"
+
+	// ProfileGroupKey is the configuration key of the profile groups, an active group activates its profiles
+	// after itself. Groups are defined in the application file and may contain other groups.
+	//
+	//	profiles:
+	//	  group:
+	//	    local: dev, mysql-local
+	//	    ci: [test, mysql-local]
+	ProfileGroupKey = "profiles.group"
+
+	profileActivationParent = "config.activate"
+)
+
+// profileGroups returns the profile groups of the configuration.
+func profileGroups(v *viper.Viper) map[string][]string {
+	groups := make(map[string][]string)
+	for name, members := range v.GetStringMap(ProfileGroupKey) {
+		groups[name] = stringList(members)
+	}
+	return groups
+}
+
+// expandProfiles returns the profiles, each one followed by the members of its group, without duplicates.
+func expandProfiles(profiles []string, groups map[string][]string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+
+	var add func(profile string)
+	add = func(profile string) {
+		if seen[profile] {
+			return
+		}
+		seen[profile] = true
+		expanded = append(expanded, profile)
+		for _, member := range groups[strings.ToLower(profile)] {
+			add(member)
+		}
+	}
+
+	for _, p := range profiles {
+		add(p)
+	}
+	return expanded
+}
+
+// matchProfiles evaluates the profile expression against the active profiles, see ProfileActivationKey.
+func matchProfiles(expr string, profiles []string) (bool, error) {
+	active := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		active[p] = true
+	}
+
+	p := &profileParser{tokens: profileTokens(expr), active: active}
+	if len(p.tokens) == 0 {
+		return false, fmt.Errorf("empty profile expression")
+	}
+	ok, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("invalid profile expression %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return ok, nil
+}
+
+// profileParser evaluates the tokens of a profile expression:
+//
+//	or  = and { ("|" | ",") and }
+//	and = not { "&" not }
+//	not = "!" not | "(" or ")" | profile
+type profileParser struct {
+	tokens []string
+	pos    int
+	active map[string]bool
+}
+
+func (p *profileParser) or() (bool, error) {
+	ok, err := p.and()
+	if err != nil {
+		return false, err
+	}
+	for p.next("|") || p.next(",") {
+		right, err := p.and()
+		if err != nil {
+			return false, err
+		}
+		ok = ok || right
+	}
+	return ok, nil
+}
+
+func (p *profileParser) and() (bool, error) {
+	ok, err := p.not()
+	if err != nil {
+		return false, err
+	}
+	for p.next("&") {
+		right, err := p.not()
+		if err != nil {
+			return false, err
+		}
+		ok = ok && right
+	}
+	return ok, nil
+}
+
+func (p *profileParser) not() (bool, error) {
+	if p.pos >= len(p.tokens) {
+		return false, fmt.Errorf("invalid profile expression: missing profile")
+	}
+	switch token := p.tokens[p.pos]; token {
+	case "!":
+		p.pos++
+		ok, err := p.not()
+		return !ok, err
+	case "(":
+		p.pos++
+		ok, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if !p.next(")") {
+			return false, fmt.Errorf("invalid profile expression: missing )")
+		}
+		return ok, nil
+	case "|", ",", "&", ")":
+		return false, fmt.Errorf("invalid profile expression: unexpected %q", token)
+	default:
+		p.pos++
+		return p.active[token], nil
+	}
+}
+
+func (p *profileParser) next(token string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// profileTokens splits the expression into operators and profile names.
+func profileTokens(expr string) []string {
+	var tokens []string
+	var name strings.Builder
+	flush := func() {
+		if name.Len() > 0 {
+			tokens = append(tokens, name.String())
+			name.Reset()
+		}
+	}
+
+	for _, r := range expr {
+		switch {
+		case strings.ContainsRune("!&|(),", r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchProfiles(t *testing.T) {
+	tests := []struct {
+		expr     string
+		profiles []string
+		want     bool
+		wantErr  string
+	}{
+		{expr: "dev", profiles: []string{"dev"}, want: true},
+		{expr: "dev", profiles: []string{"prod"}, want: false},
+		{expr: "dev | test", profiles: []string{"test"}, want: true},
+		{expr: "dev, test", profiles: []string{"test"}, want: true},
+		{expr: "dev & mysql", profiles: []string{"dev"}, want: false},
+		{expr: "dev & mysql", profiles: []string{"mysql", "dev"}, want: true},
+		{expr: "!prod", profiles: nil, want: true},
+		{expr: "!prod", profiles: []string{"prod"}, want: false},
+		{expr: "dev & !(mysql | postgres)", profiles: []string{"dev", "postgres"}, want: false},
+		{expr: "dev & !(mysql | postgres)", profiles: []string{"dev"}, want: true},
+		{expr: "!dev | test & mysql", profiles: []string{"dev", "test"}, want: false},
+		{expr: "", wantErr: "empty profile expression"},
+		{expr: "dev &", wantErr: "missing profile"},
+		{expr: "dev test", wantErr: "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := matchProfiles(tt.expr, tt.profiles)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("matchProfiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchProfiles() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchProfiles(%q, %v) = %t, want %t", tt.expr, tt.profiles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_LoadConfig_profiles(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"application.yaml": `
+profiles:
+  group:
+    local: dev, mysql-local
+datasource: none
+level: info
+port: 8080
+---
+config:
+  activate:
+    on-profile: dev | test
+port: 9090
+---
+config:
+  activate:
+    on-profile: "!prod"
+level: debug
+---
+config:
+  activate:
+    on-profile: mysql-local
+datasource: mysql
+`,
+		"application-dev.yaml": "from_profile_file: dev\n",
+	})
+
+	tests := []struct {
+		name         string
+		profiles     []string
+		want         map[string]string
+		wantProfiles string
+	}{
+		{
+			name:         "without profiles",
+			want:         map[string]string{"port": "8080", "level": "debug", "datasource": "none"},
+			wantProfiles: "",
+		},
+		{
+			name:         "activated documents",
+			profiles:     []string{"test"},
+			want:         map[string]string{"port": "9090", "level": "debug", "datasource": "none"},
+			wantProfiles: "test",
+		},
+		{
+			name:         "negated activation",
+			profiles:     []string{"prod"},
+			want:         map[string]string{"port": "8080", "level": "info", "datasource": "none"},
+			wantProfiles: "prod",
+		},
+		{
+			name:     "group expanded to its profiles",
+			profiles: []string{"local"},
+			want: map[string]string{
+				"port": "9090", "level": "debug", "datasource": "mysql", "from_profile_file": "dev",
+			},
+			wantProfiles: "local,dev,mysql-local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvActiveProfiles, "")
+			a := New()
+			if err := a.LoadConfig(dir, tt.profiles...); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			for key, want := range tt.want {
+				if got := a.Config().GetString(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if got := a.Config().GetString(EnvActiveProfiles); got != tt.wantProfiles {
+				t.Errorf("%s = %q, want %q", EnvActiveProfiles, got, tt.wantProfiles)
+			}
+		})
+	}
+}
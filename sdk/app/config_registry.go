@@ -0,0 +1,51 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ConfigDescriptor declares the structure of the configuration under a key.
+type ConfigDescriptor struct {
+	Key       string
+	Prototype interface{}
+}
+
+// RegisterConfig declares the structure of the configuration under the key in the default application.
+func RegisterConfig(key string, prototype interface{}) {
+	defaultApp.RegisterConfig(key, prototype)
+}
+
+// RegisterConfig declares the structure of the configuration under the key, e.g. the Config struct of a server.
+// The registered configurations are decoded by the config validate command.
+// Several prototypes can be registered under the same key, a prototype registered twice is ignored.
+func (a *App) RegisterConfig(key string, prototype interface{}) {
+	a.configsMu.Lock()
+	defer a.configsMu.Unlock()
+
+	t := reflect.TypeOf(prototype)
+	for _, d := range a.configs {
+		if d.Key == key && reflect.TypeOf(d.Prototype) == t {
+			return
+		}
+	}
+	a.configs = append(a.configs, ConfigDescriptor{Key: key, Prototype: prototype})
+}
+
+// Configs returns the registered configurations sorted by key.
+func (a *App) Configs() []ConfigDescriptor {
+	a.configsMu.RLock()
+	defer a.configsMu.RUnlock()
+
+	configs := make([]ConfigDescriptor, len(a.configs))
+	copy(configs, a.configs)
+	sort.SliceStable(configs, func(i, j int) bool {
+		return configs[i].Key < configs[j].Key
+	})
+	return configs
+}
+
+// newConfigValue creates a pointer to a zero value of the prototype type.
+func (d ConfigDescriptor) newConfigValue() interface{} {
+	return reflect.New(reflect.TypeOf(d.Prototype)).Interface()
+}
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SchemaDraft is the JSON Schema version of ConfigSchema.
+	SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+	placeholderRef = "#/definitions/placeholder"
+	durationRef    = "#/definitions/duration"
+)
+
+// ConfigSchema returns the JSON Schema of the configuration, built from the registered configurations.
+// The shape of each key comes from the mapstructure tags of its prototype, and the validate and default tags
+// are translated to their JSON Schema keywords. Scalars accept a ${...} placeholder as well.
+// Several prototypes registered under the same key are merged.
+func (a *App) ConfigSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, d := range a.Configs() {
+		s := typeSchema(reflect.TypeOf(d.Prototype))
+		if s == nil {
+			continue
+		}
+		setSchemaProperty(properties, strings.Split(d.Key, "."), s)
+	}
+
+	return map[string]interface{}{
+		"$schema":    SchemaDraft,
+		"title":      a.Name(),
+		"type":       "object",
+		"properties": properties,
+		"definitions": map[string]interface{}{
+			"placeholder": map[string]interface{}{
+				"type":    "string",
+				"pattern": `\$\{.+\}`,
+			},
+			"duration": map[string]interface{}{
+				"type":    "string",
+				"pattern": `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$|^0$`,
+			},
+		},
+	}
+}
+
+// setSchemaProperty sets the schema of the key path, e.g. spring.cloud.config, merging it with the existing one.
+func setSchemaProperty(properties map[string]interface{}, path []string, s map[string]interface{}) {
+	key := path[0]
+	if len(path) > 1 {
+		children := make(map[string]interface{})
+		setSchemaProperty(children, path[1:], s)
+		s = map[string]interface{}{"type": "object", "properties": children}
+	}
+	if existing, ok := properties[key].(map[string]interface{}); ok {
+		s = mergeSchema(existing, s)
+	}
+	properties[key] = s
+}
+
+// typeSchema returns the schema of the type, nil when the type can not be configured, e.g. an interface.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return anyOf(map[string]interface{}{"$ref": durationRef}, map[string]interface{}{"type": "integer"})
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return anyOf(map[string]interface{}{"type": "boolean"})
+	case isInt(t.Kind()):
+		return anyOf(map[string]interface{}{"type": "integer"})
+	case t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		return anyOf(map[string]interface{}{"type": "number"})
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		items := typeSchema(t.Elem())
+		if items == nil {
+			return nil
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case t.Kind() == reflect.Map:
+		values := typeSchema(t.Elem())
+		if values == nil {
+			return nil
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}
+	case t.Kind() == reflect.Struct:
+		return structSchema(t)
+	}
+	return nil
+}
+
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, squash := fieldName(f)
+		s := typeSchema(f.Type)
+		if s == nil {
+			continue
+		}
+		if p, ok := s["properties"].(map[string]interface{}); ok && squash {
+			for k, v := range p {
+				properties[k] = v
+			}
+			if r, ok := s["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			continue
+		}
+
+		if applyTags(s, f) {
+			required = append(required, name)
+		}
+		properties[name] = s
+	}
+
+	s := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// applyTags translates the default, secret and validate tags of the field, it returns whether the field is required.
+// A string constrained by its rules, e.g. an enum or a pattern, accepts a placeholder as well.
+func applyTags(s map[string]interface{}, f reflect.StructField) bool {
+	if s["type"] == "string" && constrained(f) {
+		wrapped := anyOf(map[string]interface{}{"type": "string"})
+		delete(s, "type")
+		s["anyOf"] = wrapped["anyOf"]
+	}
+
+	target := s
+	if alternatives, ok := s["anyOf"].([]interface{}); ok {
+		target = alternatives[0].(map[string]interface{})
+	}
+
+	if def, ok := f.Tag.Lookup("default"); ok {
+		s["default"] = defaultValue(f.Type, def)
+	}
+	if f.Tag.Get("secret") == "true" {
+		s["writeOnly"] = true
+	}
+
+	required := false
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			if keyword := rangeKeyword(f.Type, name); keyword != "" {
+				if n, err := strconv.ParseFloat(arg, 64); err == nil {
+					target[keyword] = n
+				}
+			}
+		case "oneof":
+			target["enum"] = strings.Fields(arg)
+		case "url":
+			target["format"] = "uri"
+		case "hostport":
+			target["pattern"] = `^[^:]*:[0-9]+$`
+		}
+	}
+	return required
+}
+
+// constrained returns whether the validate tag of the field has a rule restricting its values.
+func constrained(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(rule), "="); name != "" && name != "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeKeyword returns the JSON Schema keyword of a min or max rule on the type.
+func rangeKeyword(t reflect.Type, rule string) string {
+	var min, max string
+	switch {
+	case t == durationType:
+		return ""
+	case isInt(t.Kind()), t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		min, max = "minimum", "maximum"
+	case t.Kind() == reflect.String:
+		min, max = "minLength", "maxLength"
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		min, max = "minItems", "maxItems"
+	case t.Kind() == reflect.Map:
+		min, max = "minProperties", "maxProperties"
+	default:
+		return ""
+	}
+	if rule == "min" {
+		return min
+	}
+	return max
+}
+
+func defaultValue(t reflect.Type, def string) interface{} {
+	v := reflect.New(t).Elem()
+	if t == durationType || setString(v, def) != nil {
+		return def
+	}
+	return v.Interface()
+}
+
+// anyOf accepts any of the schemas or a placeholder.
+func anyOf(schemas ...map[string]interface{}) map[string]interface{} {
+	alternatives := make([]interface{}, 0, len(schemas)+1)
+	for _, s := range schemas {
+		alternatives = append(alternatives, s)
+	}
+	return map[string]interface{}{
+		"anyOf": append(alternatives, map[string]interface{}{"$ref": placeholderRef}),
+	}
+}
+
+// mergeSchema merges the properties of two object schemas.
+func mergeSchema(a, b map[string]interface{}) map[string]interface{} {
+	if a["type"] != "object" || b["type"] != "object" {
+		return a
+	}
+
+	if ap, ok := a["properties"].(map[string]interface{}); ok {
+		if bp, ok := b["properties"].(map[string]interface{}); ok {
+			for k, v := range bp {
+				if existing, ok := ap[k].(map[string]interface{}); ok {
+					if vs, ok := v.(map[string]interface{}); ok {
+						ap[k] = mergeSchema(existing, vs)
+						continue
+					}
+				}
+				ap[k] = v
+			}
+		}
+	}
+
+	if br, ok := b["required"].([]string); ok {
+		required, _ := a["required"].([]string)
+		for _, r := range br {
+			if !containsString(required, r) {
+				required = append(required, r)
+			}
+		}
+		sort.Strings(required)
+		a["required"] = required
+	}
+
+	if aa, ok := a["additionalProperties"].(map[string]interface{}); ok {
+		if ba, ok := b["additionalProperties"].(map[string]interface{}); ok {
+			a["additionalProperties"] = mergeSchema(aa, ba)
+		}
+	}
+	return a
+}
+
+func isInt(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApp_ConfigSchema_placeholders(t *testing.T) {
+	type prototype struct {
+		Name    string `mapstructure:"name"`
+		Format  string `mapstructure:"format" validate:"oneof=text json"`
+		Address string `mapstructure:"address" validate:"required,hostport"`
+		URL     string `mapstructure:"url" validate:"url"`
+		Port    int    `mapstructure:"port" validate:"max=65535"`
+	}
+	placeholder := map[string]interface{}{"$ref": placeholderRef}
+
+	a := New()
+	a.RegisterConfig("test", prototype{})
+	properties := a.ConfigSchema()["properties"].(map[string]interface{})["test"].(map[string]interface{})["properties"].(map[string]interface{})
+
+	tests := []struct {
+		key  string
+		want map[string]interface{}
+	}{
+		{key: "name", want: map[string]interface{}{"type": "string"}},
+		{key: "format", want: map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": []string{"text", "json"}}, placeholder,
+		}}},
+		{key: "address", want: map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "pattern": `^[^:]*:[0-9]+$`}, placeholder,
+		}}},
+		{key: "url", want: map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "string", "format": "uri"}, placeholder,
+		}}},
+		{key: "port", want: map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "integer", "maximum": float64(65535)}, placeholder,
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := properties[tt.key]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schema of %s = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Priorities of the configuration, a source of a higher priority takes precedence.
+const (
+	// ConfigPriorityFiles is the priority of the application files, the sources of a lower priority are loaded
+	// before them, their profiles are only known from the environment variables.
+	ConfigPriorityFiles = 0
+	// ConfigPrioritySpringCloudConfig is the priority of the spring cloud config server.
+	ConfigPrioritySpringCloudConfig = 50
+	// ConfigPriorityEnv is the priority of the environment variables, the sources of a higher priority
+	// take precedence over them.
+	ConfigPriorityEnv = 100
+)
+
+type (
+	// ConfigSource provides configuration values from a backend, e.g. Consul KV, etcd or Vault KV.
+	// The sources are registered with RegisterConfigSource.
+	ConfigSource interface {
+		// Name identifies the source in the errors and in the origin of its keys.
+		Name() string
+		// Priority orders the source relative to the other sources, the application files (ConfigPriorityFiles)
+		// and the environment variables (ConfigPriorityEnv).
+		Priority() int
+		// Load returns the values of the source, the last property source takes precedence. The configuration
+		// holds the values of lower priority, its placeholders resolved, e.g. to read the address of the backend.
+		Load(config *ApplicationConfig) ([]PropertySource, error)
+	}
+
+	// ConfigSourceWatcher is implemented by the sources notifying their changes, the configuration is reloaded
+	// when it is watched, see WatchConfig.
+	ConfigSourceWatcher interface {
+		// Watch calls changed when the values of the source change, until the context is done.
+		Watch(ctx context.Context, config *ApplicationConfig, changed func()) error
+	}
+
+	// PropertySource holds the values of a source, as nested maps or dotted keys, e.g. servers[0].host.
+	PropertySource struct {
+		Name   string
+		Values map[string]interface{}
+	}
+
+	// SourceOption configures the built-in config sources, see NewHTTPSource and NewDirectorySource.
+	SourceOption func(*sourceOptions)
+
+	sourceOptions struct {
+		priority     int
+		optional     bool
+		pollInterval time.Duration
+		header       http.Header
+		client       *http.Client
+	}
+)
+
+// RegisterConfigSource adds the source to the configuration of the default application.
+// See App.RegisterConfigSource.
+func RegisterConfigSource(source ConfigSource) {
+	defaultApp.RegisterConfigSource(source)
+}
+
+// RegisterConfigSource adds the source to the configuration of the application, the sources of the same
+// priority are loaded in the order they are registered. The spring cloud config server source is registered
+// by default.
+//
+//	a.RegisterConfigSource(app.NewHTTPSource("consul", "${CONSUL_ADDR:http://localhost:8500}/v1/kv/item?raw",
+//		app.WithSourceOptional(), app.WithSourcePollInterval(time.Minute)))
+func (a *App) RegisterConfigSource(source ConfigSource) {
+	a.sourcesMu.Lock()
+	defer a.sourcesMu.Unlock()
+	a.sources = append(a.sources, source)
+}
+
+// configSources returns the sources registered in the application sorted by priority.
+func (a *App) configSources() []ConfigSource {
+	a.sourcesMu.RLock()
+	defer a.sourcesMu.RUnlock()
+	sorted := append([]ConfigSource(nil), a.sources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+	return sorted
+}
+
+// configSources returns the sources of the application of the configuration, the ones of the default
+// application for a configuration created without application, e.g. with LoadApplicationConfig.
+func (c *ApplicationConfig) configSources() []ConfigSource {
+	if c.sources != nil {
+		return c.sources()
+	}
+	return defaultApp.configSources()
+}
+
+// WithSourcePriority sets the priority of the source, ConfigPrioritySpringCloudConfig by default.
+func WithSourcePriority(priority int) SourceOption {
+	return func(o *sourceOptions) {
+		o.priority = priority
+	}
+}
+
+// WithSourceOptional ignores the source when it can not be loaded, the error is printed.
+func WithSourceOptional() SourceOption {
+	return func(o *sourceOptions) {
+		o.optional = true
+	}
+}
+
+// WithSourcePollInterval watches an HTTP source by fetching it every interval.
+func WithSourcePollInterval(interval time.Duration) SourceOption {
+	return func(o *sourceOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithSourceHeader adds a header to the requests of an HTTP source, its placeholders are resolved.
+func WithSourceHeader(key, value string) SourceOption {
+	return func(o *sourceOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithSourceHTTPClient sets the client of an HTTP source, a client with a 10s timeout by default.
+func WithSourceHTTPClient(client *http.Client) SourceOption {
+	return func(o *sourceOptions) {
+		o.client = client
+	}
+}
+
+func newSourceOptions(opts []SourceOption) sourceOptions {
+	o := sourceOptions{
+		priority: ConfigPrioritySpringCloudConfig,
+		header:   make(http.Header),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// mergeSource merges the values of the source, the values of a source taking precedence over the environment
+// variables are set, except the command line ones.
+func (r *resolvedConfig) mergeSource(source ConfigSource) error {
+	pss, err := source.Load(r.snapshot())
+	if err != nil {
+		return fmt.Errorf("config source %s: %w", source.Name(), err)
+	}
+
+	for _, ps := range pss {
+		settings := expandProperties(ps.Values)
+		origin := source.Name()
+		if ps.Name != "" {
+			origin = fmt.Sprintf("%s %s", origin, ps.Name)
+		}
+
+		if source.Priority() > ConfigPriorityEnv {
+			for key, value := range flattenSettings("", settings) {
+				if _, ok := r.commandLine[key]; ok {
+					continue
+				}
+				r.viper.Set(key, value)
+				r.pinned[key] = true
+			}
+		} else if err := r.viper.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("config source %s: unable to merge %s: %w", source.Name(), ps.Name, err)
+		}
+		recordOrigins(r.origins, "", settings, origin)
+	}
+	return nil
+}
+
+// flattenSettings returns the values of the nested settings by dotted key.
+func flattenSettings(prefix string, settings map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	for k, value := range settings {
+		key := joinKey(prefix, strings.ToLower(k))
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			for fk, fv := range flattenSettings(key, m) {
+				flat[fk] = fv
+			}
+			continue
+		}
+		flat[key] = value
+	}
+	return flat
+}
+
+// snapshot returns the configuration loaded so far, its placeholders resolved, for the sources to read
+// their settings.
+func (r *resolvedConfig) snapshot() *ApplicationConfig {
+	v := r.viper
+	sv := viper.New()
+	sv.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	sv.AutomaticEnv()
+	_ = sv.MergeConfigMap(v.AllSettings())
+	// the active profiles expanded by loadFile and the pinned values take precedence over the environment variables.
+	sv.Set(EnvActiveProfiles, v.GetString(EnvActiveProfiles))
+	for key := range r.pinned {
+		sv.Set(key, v.Get(key))
+	}
+	// the problems are reported once the whole configuration is resolved.
+	_ = resolveValues(sv, r.resolvers)
+
+	c := newApplicationConfig()
+	c.viper = sv
+	c.resolvers = r.resolvers
+	c.loaded = true
+	return c
+}
+
+// resolveString resolves the placeholders of s with the configuration.
+func resolveString(config *ApplicationConfig, s string) (string, error) {
+	p := &placeholders{v: config.current(), resolvers: config.placeholderResolvers(), resolved: make(map[string]interface{})}
+	return p.stringValue(s, nil)
+}
+
+// HTTPSource loads a JSON object, with nested or dotted keys, from an HTTP endpoint.
+type HTTPSource struct {
+	name string
+	url  string
+	opts sourceOptions
+
+	mu   sync.Mutex
+	hash [sha256.Size]byte
+}
+
+// NewHTTPSource creates a source fetching the JSON object of the url, its placeholders are resolved.
+// It is watched when a poll interval is set, see WithSourcePollInterval.
+func NewHTTPSource(name, url string, opts ...SourceOption) *HTTPSource {
+	return &HTTPSource{name: name, url: url, opts: newSourceOptions(opts)}
+}
+
+func (s *HTTPSource) Name() string {
+	return s.name
+}
+
+func (s *HTTPSource) Priority() int {
+	return s.opts.priority
+}
+
+// Load fetches the JSON object of the endpoint.
+func (s *HTTPSource) Load(config *ApplicationConfig) ([]PropertySource, error) {
+	body, u, err := s.fetch(config)
+	if err != nil {
+		if s.opts.optional {
+			fmt.Fprintf(os.Stderr, "config source %s: %v, continuing without it\n", s.name, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.hash = sha256.Sum256(body)
+	s.mu.Unlock()
+
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", u, err)
+	}
+	return []PropertySource{{Name: u, Values: values}}, nil
+}
+
+// Watch fetches the endpoint every poll interval, changed is called when the response changes.
+func (s *HTTPSource) Watch(ctx context.Context, config *ApplicationConfig, changed func()) error {
+	if s.opts.pollInterval <= 0 {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.opts.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				body, _, err := s.fetch(config)
+				if err != nil {
+					log.Printf("config source %s: %v", s.name, err)
+					continue
+				}
+				s.mu.Lock()
+				hash := sha256.Sum256(body)
+				modified := hash != s.hash
+				s.mu.Unlock()
+				if modified {
+					changed()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *HTTPSource) fetch(config *ApplicationConfig) ([]byte, string, error) {
+	u, err := resolveString(config, s.url)
+	if err != nil {
+		return nil, s.url, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, u, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, values := range s.opts.header {
+		for _, value := range values {
+			resolved, err := resolveString(config, value)
+			if err != nil {
+				return nil, u, fmt.Errorf("header %s: %w", key, err)
+			}
+			req.Header.Add(key, resolved)
+		}
+	}
+
+	resp, err := s.opts.client.Do(req)
+	if err != nil {
+		return nil, u, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, u, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, u, err
+}
+
+// DirectorySource loads a directory tree where each file is a key and its content the value, as the Kubernetes
+// ConfigMap and Secret volumes, see ImportConfigKey for the configtree imports of the files.
+type DirectorySource struct {
+	dir  string
+	opts sourceOptions
+}
+
+// NewDirectorySource creates a source reading the files of the directory, it is watched for changes.
+func NewDirectorySource(dir string, opts ...SourceOption) *DirectorySource {
+	return &DirectorySource{dir: dir, opts: newSourceOptions(opts)}
+}
+
+func (s *DirectorySource) Name() string {
+	return fmt.Sprintf("directory %s", s.dir)
+}
+
+func (s *DirectorySource) Priority() int {
+	return s.opts.priority
+}
+
+// Load reads the files of the directory, a missing directory is ignored when the source is optional.
+func (s *DirectorySource) Load(*ApplicationConfig) ([]PropertySource, error) {
+	values := make(map[string]interface{})
+	if err := readConfigTree(s.dir, "", values, make(map[string]string)); err != nil {
+		if s.opts.optional && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []PropertySource{{Values: values}}, nil
+}
+
+// Watch calls changed when a file of the directory, or of its sub directories, changes.
+func (s *DirectorySource) Watch(ctx context.Context, _ *ApplicationConfig, changed func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil && !(s.opts.optional && errors.Is(err, fs.ErrNotExist)) {
+		_ = watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				changed()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("config source %s watcher error: %v", s.Name(), err)
+			}
+		}
+	}()
+	return nil
+}
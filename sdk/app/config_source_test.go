@@ -0,0 +1,53 @@
+package app
+
+import "testing"
+
+// staticSource is a ConfigSource returning fixed values.
+type staticSource struct {
+	name     string
+	priority int
+	values   map[string]interface{}
+}
+
+func (s staticSource) Name() string {
+	return s.name
+}
+
+func (s staticSource) Priority() int {
+	return s.priority
+}
+
+func (s staticSource) Load(*ApplicationConfig) ([]PropertySource, error) {
+	return []PropertySource{{Name: "values", Values: s.values}}, nil
+}
+
+func TestApp_RegisterConfigSource(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"application.yaml": "greeting: from file\n"})
+
+	withSource := New()
+	withSource.RegisterConfigSource(staticSource{
+		name:     "kv",
+		priority: ConfigPrioritySpringCloudConfig,
+		values:   map[string]interface{}{"greeting": "from kv"},
+	})
+	without := New()
+
+	tests := []struct {
+		name string
+		app  *App
+		want string
+	}{
+		{name: "loaded from the sources of the application", app: withSource, want: "from kv"},
+		{name: "not loaded from the sources of another application", app: without, want: "from file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.app.LoadConfig(dir); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := tt.app.Config().GetString("greeting"); got != tt.want {
+				t.Errorf("greeting = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// WatchConfigKey is the configuration key of the configuration watching.
+const WatchConfigKey = "config.watch"
+
+type (
+	// WatchConfig configures the reload of the configuration while the application is served.
+	//
+	//	config:
+	//	  watch:
+	//	    enabled: true
+	//	    debounce: 500ms    # wait for the file changes to settle before reloading
+	//	    poll_interval: 30s # reload periodically, e.g. to fetch the changes of the spring cloud config server
+	WatchConfig struct {
+		Enabled      bool          `mapstructure:"enabled"`
+		Debounce     time.Duration `mapstructure:"debounce" default:"500ms"`
+		PollInterval time.Duration `mapstructure:"poll_interval" validate:"min=0s"`
+	}
+
+	subscriber struct {
+		key string
+		fn  func(old, new interface{})
+	}
+)
+
+// OnChange registers a function called when the value of the key changes on a reload, the value of a parent key
+// holds its children. The functions are called sequentially, in the goroutine reloading the configuration.
+//
+//	config.OnChange("logger.level", func(old, new interface{}) {
+//		log.Printf("logging level changed from %v to %v", old, new)
+//	})
+func (c *ApplicationConfig) OnChange(key string, fn func(old, new interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, subscriber{key: strings.ToLower(key), fn: fn})
+}
+
+// Reload resolves the configuration again from its files and the config sources, then notifies the changes to the
+// OnChange functions. The values set with Set and on the command line are kept.
+// The current configuration is kept when the new one can not be resolved or an application configuration is invalid.
+// A configuration that was not loaded from files, e.g. with ParseApplicationConfig, is not reloaded.
+func (c *ApplicationConfig) Reload() error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	c.mu.RLock()
+	reloadable, configPath, profiles, commandLine := c.reloadable, c.configPath, c.profiles, c.commandLine
+	overrides := make(map[string]interface{}, len(c.overrides))
+	for k, v := range c.overrides {
+		overrides[k] = v
+	}
+	c.mu.RUnlock()
+	if !reloadable {
+		return nil
+	}
+
+	r, err := loadConfig(configPath, profiles, commandLine, c.configSources(), c.placeholderResolvers())
+	if err != nil {
+		return err
+	}
+	next := &ApplicationConfig{overrides: overrides, descriptors: c.descriptors}
+	next.apply(r)
+	if err := next.validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	previous, v := c.viper, r.viper
+	c.apply(r)
+	subscribers := append([]subscriber(nil), c.subscribers...)
+	c.mu.Unlock()
+
+	for _, s := range subscribers {
+		old, current := previous.Get(s.key), v.Get(s.key)
+		if !reflect.DeepEqual(old, current) {
+			s.fn(old, current)
+		}
+	}
+	return nil
+}
+
+// Watch reloads the configuration when the application files of its directories, the imported files or the files
+// of the imported trees change, when a config source notifies a change, see ConfigSourceWatcher, and every poll
+// interval when it is set. The Kubernetes ConfigMap and Secret volumes, updated through a ..data symbolic link, are supported.
+// It stops watching when the context is done.
+func (c *ApplicationConfig) Watch(ctx context.Context, cfg WatchConfig) error {
+	c.mu.RLock()
+	reloadable, dirs := c.reloadable, configDirs(c.configPath, c.origins)
+	c.mu.RUnlock()
+	if !reloadable {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil && !os.IsNotExist(err) {
+			_ = watcher.Close()
+			return err
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	changed := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	for _, source := range c.configSources() {
+		if w, ok := source.(ConfigSourceWatcher); ok {
+			if err := w.Watch(ctx, c, changed); err != nil {
+				_ = watcher.Close()
+				return fmt.Errorf("unable to watch config source %s: %w", source.Name(), err)
+			}
+		}
+	}
+
+	go c.watch(ctx, watcher, changes, cfg)
+	return nil
+}
+
+func (c *ApplicationConfig) watch(ctx context.Context, watcher *fsnotify.Watcher, changes <-chan struct{}, cfg WatchConfig) {
+	defer watcher.Close()
+
+	var poll <-chan time.Time
+	if cfg.PollInterval > 0 {
+		ticker := time.NewTicker(cfg.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if c.isConfigFile(event.Name) {
+				debounce = time.After(cfg.Debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("configuration watcher error: %v", err)
+		case <-changes:
+			debounce = time.After(cfg.Debounce)
+		case <-debounce:
+			debounce = nil
+			if err := c.Reload(); err != nil {
+				log.Printf("configuration not reloaded: %s", c.RedactString(err.Error()))
+				continue
+			}
+			log.Printf("configuration reloaded")
+		case <-poll:
+			if err := c.Reload(); err != nil {
+				log.Printf("configuration not reloaded: %s", c.RedactString(err.Error()))
+			}
+		}
+	}
+}
+
+// configDirs returns the directories the configuration files are loaded from, see loadFile, and the directories of
+// the files the keys originate from.
+func configDirs(configPath string, origins map[string]string) []string {
+	candidates := []string{configPath, "./config", "."}
+	for _, origin := range origins {
+		if info, err := os.Stat(origin); err == nil && !info.IsDir() {
+			candidates = append(candidates, filepath.Dir(origin))
+		}
+	}
+
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range candidates {
+		abs, err := filepath.Abs(dir)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		dirs = append(dirs, abs)
+	}
+	return dirs
+}
+
+// isConfigFile reports whether the path is an application file, the ..data link of a Kubernetes volume,
+// or a file a key originates from.
+func (c *ApplicationConfig) isConfigFile(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, "..data") {
+		return true
+	}
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if strings.HasPrefix(name, "application") && containsString(viper.SupportedExts, ext) {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, origin := range c.origins {
+		if abs, err := filepath.Abs(origin); err == nil && abs == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// changes records the notifications of the OnChange functions.
+type changes struct {
+	mu      sync.Mutex
+	entries []string
+	changed chan struct{}
+}
+
+func newChanges() *changes {
+	return &changes{changed: make(chan struct{}, 16)}
+}
+
+func (c *changes) subscribe(cfg *ApplicationConfig, keys ...string) {
+	for _, key := range keys {
+		key := key
+		cfg.OnChange(key, func(old, new interface{}) {
+			c.mu.Lock()
+			c.entries = append(c.entries, fmt.Sprintf("%s %v %v", key, old, new))
+			c.mu.Unlock()
+			c.changed <- struct{}{}
+		})
+	}
+}
+
+func (c *changes) list() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.entries...)
+}
+
+func TestApplicationConfig_Reload(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		next    string
+		sets    []string
+		set     map[string]interface{}
+		want    map[string]string
+		wantErr bool
+		// wantChanges are the notifications of the greeting, level and db keys.
+		wantChanges []string
+	}{
+		{
+			name:        "changed values notified with the old and new values",
+			config:      "greeting: hello\nlevel: info\n",
+			next:        "greeting: bonjour\nlevel: info\n",
+			want:        map[string]string{"greeting": "bonjour", "level": "info"},
+			wantChanges: []string{"greeting hello bonjour"},
+		},
+		{
+			name:        "parent key notified of the changes of its children",
+			config:      "db:\n  host: a\n",
+			next:        "db:\n  host: b\n",
+			want:        map[string]string{"db.host": "b"},
+			wantChanges: []string{"db map[host:a] map[host:b]"},
+		},
+		{
+			name:    "invalid configuration rejected",
+			config:  "greeting: hello\n",
+			next:    "greeting: bonjour\npool:\n  mode: random\n",
+			want:    map[string]string{"greeting": "hello", "pool.mode": ""},
+			wantErr: true,
+		},
+		{
+			name:        "command line values kept",
+			config:      "greeting: hello\nlevel: info\n",
+			next:        "greeting: bonjour\nlevel: debug\n",
+			sets:        []string{"greeting=from set"},
+			want:        map[string]string{"greeting": "from set", "level": "debug"},
+			wantChanges: []string{"level info debug"},
+		},
+		{
+			name:   "values set kept",
+			config: "greeting: hello\n",
+			next:   "greeting: bonjour\n",
+			set:    map[string]interface{}{"greeting": "from Set"},
+			want:   map[string]string{"greeting": "from Set"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{"application.yaml": tt.config})
+			a := New()
+			a.RegisterConfig("pool", poolConfig{})
+			overrides, err := commandLineOverrides(tt.sets, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a.Config().setCommandLine(overrides)
+			if err := a.LoadConfig(dir); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			cfg := a.Config()
+			for k, v := range tt.set {
+				cfg.Set(k, v)
+			}
+			c := newChanges()
+			c.subscribe(cfg, "greeting", "level", "db")
+
+			if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte(tt.next), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			for key, want := range tt.want {
+				if got := cfg.GetString(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if got := c.list(); !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("changes = %q, want %q", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestApplicationConfig_Reload_notLoadedFromFiles(t *testing.T) {
+	cfg, err := ParseApplicationConfig([]byte("greeting: hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newChanges()
+	c.subscribe(cfg, "greeting")
+	cfg.Set("greeting", "bonjour")
+
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := cfg.GetString("greeting"); got != "bonjour" {
+		t.Errorf("greeting = %q, want bonjour", got)
+	}
+	if got := c.list(); len(got) != 0 {
+		t.Errorf("changes = %q, want none", got)
+	}
+}
+
+func TestApplicationConfig_Watch(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"application.yaml": "greeting: hello\n"})
+	a := New()
+	if err := a.LoadConfig(dir); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg := a.Config()
+	c := newChanges()
+	c.subscribe(cfg, "greeting")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := cfg.Watch(ctx, WatchConfig{Enabled: true, Debounce: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the files other than the application files do not reload the configuration.
+	write("notes.txt", "greeting: ignored\n")
+	// the changes settling within the debounce are reloaded at once.
+	for _, greeting := range []string{"hola", "ciao", "bonjour"} {
+		write("application.yaml", "greeting: "+greeting+"\n")
+	}
+
+	select {
+	case <-c.changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	select {
+	case <-c.changed:
+	case <-time.After(300 * time.Millisecond):
+	}
+	if got, want := c.list(), []string{"greeting hello bonjour"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+}
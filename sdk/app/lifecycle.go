@@ -0,0 +1,152 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const StartupConfigKey = "startup"
+
+// ErrRestartRequired is returned by Reloadable when the reloaded configuration requires a restart to be applied.
+var ErrRestartRequired = errors.New("restart required")
+
+type (
+	// Readiness is implemented by servers that are able to report when they are ready to accept work.
+	// The channel must be closed once the server is ready, the servers depending on it are not started
+	// before that.
+	Readiness interface {
+		Ready() <-chan struct{}
+	}
+
+	// Listener is implemented by servers listening on a network address. Once the server is ready, the address
+	// it is bound to (e.g. the port picked by the kernel when configured with port 0) is reported by its
+	// ServerStatus, see App.ServerAddr.
+	Listener interface {
+		Addr() net.Addr
+	}
+
+	// Initializer is implemented by components that need to run a step when the application starts
+	// (e.g. applying migrations). Components are initialized before the servers that do not depend on them
+	// are started, so a worker never processes work before the migrations are applied.
+	// The components implementing io.Closer are closed in reverse startup order once the servers are stopped.
+	Initializer interface {
+		Init(config *ApplicationConfig) error
+	}
+
+	// Reloadable is implemented by servers and components able to apply a reloaded configuration without being
+	// restarted (e.g. logging levels, connection pool sizes, certificates). Reload returns an error wrapping
+	// ErrRestartRequired when a change can only be applied by restarting the application.
+	Reloadable interface {
+		Reload(config *ApplicationConfig) error
+	}
+
+	// StartupConfig configures how the servers are started.
+	//
+	//	startup:
+	//	  timeout: 1m # deadline for each server to be ready, 0 waits until the application is stopped
+	StartupConfig struct {
+		Timeout time.Duration `mapstructure:"timeout" default:"1m" validate:"min=0s"`
+	}
+
+	// RegisterOption customizes a server or component registration.
+	RegisterOption func(*registration)
+
+	registration struct {
+		dependsOn     []string
+		restartPolicy *RestartPolicy
+	}
+)
+
+// DependsOn declares the servers or components that must be started (and ready) before the registered one.
+// It also means the registered one is stopped before them.
+func DependsOn(names ...string) RegisterOption {
+	return func(r *registration) {
+		r.dependsOn = append(r.dependsOn, names...)
+	}
+}
+
+func newRegistration(opts []RegisterOption) registration {
+	var r registration
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// startupOrder sorts the servers and components topologically according to their declared dependencies.
+// Among the ones whose dependencies are satisfied, components come first so that they are initialized
+// before the servers are started.
+// Dependencies on disabled servers are ignored, dependencies on unknown names are reported as errors.
+func (r registered) startupOrder(servers map[string]Server, components map[string]Component) ([]string, error) {
+	nodes := make(map[string]bool)
+	for k := range servers {
+		nodes[k] = true
+	}
+	for k := range components {
+		nodes[k] = true
+	}
+
+	inDegree := make(map[string]int)
+	dependents := make(map[string][]string)
+	for name := range nodes {
+		inDegree[name] += 0
+		for _, dep := range r.registrations[name].dependsOn {
+			if !nodes[dep] {
+				if _, ok := r.serverFactories[dep]; ok {
+					log.Printf("%s depends on disabled server %s, ignoring dependency", name, dep)
+					continue
+				}
+				return nil, fmt.Errorf("%s depends on unknown server or component %s", name, dep)
+			}
+			inDegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var queue []string
+	for name, degree := range inDegree {
+		if degree == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	var order []string
+	for len(queue) > 0 {
+		sort.Slice(queue, func(i, j int) bool {
+			_, ci := components[queue[i]]
+			_, cj := components[queue[j]]
+			if ci != cj {
+				return ci
+			}
+			return queue[i] < queue[j]
+		})
+		name := queue[0]
+		queue = queue[1:]
+		order = append(order, name)
+
+		for _, dependent := range dependents[name] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(nodes) {
+		var cycle []string
+		for name, degree := range inDegree {
+			if degree > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+	}
+
+	return order, nil
+}
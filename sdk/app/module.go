@@ -0,0 +1,117 @@
+package app
+
+import (
+	"log"
+	"strings"
+
+	"github.com/ovargas/wizapp/sdk/health"
+)
+
+// ModulesConfigKey is the configuration key enabling and disabling the modules, a module is enabled unless
+// disabled by the configuration.
+//
+//	modules:
+//	  items:
+//	    enabled: false
+const ModulesConfigKey = "modules"
+
+type (
+	// Module is a bounded context of the application, bundling its grpc services, gateway handlers, workflows and
+	// activities, migrations, configuration and health checks. A module declares each of them by implementing the
+	// interface of the package using it: grpc_server.ServiceModule, grpc_gateway_server.HandlerModule,
+	// temporal_server.WorkerModule, sql_component.MigrationModule, ConfigModule and HealthModule.
+	Module interface {
+		Name() string
+	}
+
+	// ConfigModule is a module with its own configuration, registered under the key, see RegisterConfig.
+	ConfigModule interface {
+		Module
+		Config() (key string, prototype interface{})
+	}
+
+	// HealthModule is a module with health checks, they are registered when the application is served.
+	HealthModule interface {
+		Module
+		HealthChecks() []health.Check
+	}
+
+	// ModuleConfig enables or disables a module, see ModulesConfigKey.
+	ModuleConfig struct {
+		Enabled *bool `mapstructure:"enabled"`
+	}
+)
+
+// RegisterModule registers a module in the default application.
+func RegisterModule(module Module) {
+	defaultApp.RegisterModule(module)
+}
+
+// RegisterModule registers a module, the configuration of a ConfigModule is registered along with it.
+// The servers and components use the modules enabled by the configuration, see ApplicationConfig.Modules.
+func (a *App) RegisterModule(module Module) {
+	a.modulesMu.Lock()
+	defer a.modulesMu.Unlock()
+
+	for _, m := range a.modules {
+		if strings.EqualFold(m.Name(), module.Name()) {
+			log.Fatalf("Module %s already registered", module.Name())
+		}
+	}
+	a.modules = append(a.modules, module)
+
+	if m, ok := module.(ConfigModule); ok {
+		key, prototype := m.Config()
+		a.RegisterConfig(key, prototype)
+	}
+}
+
+// Modules returns the registered modules in registration order, enabled or not.
+func (a *App) Modules() []Module {
+	a.modulesMu.RLock()
+	defer a.modulesMu.RUnlock()
+	return append([]Module(nil), a.modules...)
+}
+
+// Modules returns the modules of the application enabled by the configuration, in registration order.
+func (c *ApplicationConfig) Modules() []Module {
+	if c.modules == nil {
+		return nil
+	}
+
+	var enabled []Module
+	for _, m := range c.modules() {
+		if c.ModuleEnabled(m.Name()) {
+			enabled = append(enabled, m)
+		}
+	}
+	return enabled
+}
+
+// ModuleEnabled reports whether the module is enabled, see ModulesConfigKey.
+func (c *ApplicationConfig) ModuleEnabled(name string) bool {
+	key := strings.Join([]string{ModulesConfigKey, strings.ToLower(name), "enabled"}, ".")
+	return !c.IsSet(key) || c.current().GetBool(key)
+}
+
+// ModulesOf returns the enabled modules implementing T, e.g. the modules declaring grpc services.
+//
+//	for _, m := range app.ModulesOf[grpc_server.ServiceModule](config) {
+//		m.RegisterServices(srv)
+//	}
+func ModulesOf[T any](c *ApplicationConfig) []T {
+	var modules []T
+	for _, m := range c.Modules() {
+		if t, ok := m.(T); ok {
+			modules = append(modules, t)
+		}
+	}
+	return modules
+}
+
+// registerModuleChecks registers the health checks of the enabled modules.
+func (a *App) registerModuleChecks(cfg *ApplicationConfig) {
+	for _, m := range ModulesOf[HealthModule](cfg) {
+		a.health.Register(m.HealthChecks()...)
+	}
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type (
+	testModule struct {
+		name string
+	}
+
+	// serviceModule is a module declaring services, ModulesOf selects it by its interface.
+	serviceModule struct {
+		testModule
+	}
+
+	configModule struct {
+		testModule
+	}
+
+	itemsConfig struct {
+		PageSize int `mapstructure:"page_size" default:"20" validate:"max=100"`
+	}
+)
+
+func (m testModule) Name() string {
+	return m.name
+}
+
+func (m serviceModule) Services() []string {
+	return []string{m.name + ".v1"}
+}
+
+func (m configModule) Config() (string, interface{}) {
+	return m.name, itemsConfig{}
+}
+
+func moduleNames[T any](modules []T) []string {
+	var names []string
+	for _, m := range modules {
+		names = append(names, interface{}(m).(Module).Name())
+	}
+	return names
+}
+
+func TestApplicationConfig_Modules(t *testing.T) {
+	modules := []Module{
+		testModule{name: "accounts"},
+		serviceModule{testModule{name: "Items"}},
+		serviceModule{testModule{name: "orders"}},
+	}
+
+	tests := []struct {
+		name         string
+		yaml         string
+		wantEnabled  []string
+		wantServices []string
+	}{
+		{
+			name:         "enabled without configuration",
+			wantEnabled:  []string{"accounts", "Items", "orders"},
+			wantServices: []string{"Items", "orders"},
+		},
+		{
+			name:         "disabled by the configuration",
+			yaml:         "modules:\n  orders:\n    enabled: false\n",
+			wantEnabled:  []string{"accounts", "Items"},
+			wantServices: []string{"Items"},
+		},
+		{
+			name:         "explicitly enabled",
+			yaml:         "modules:\n  orders:\n    enabled: true\n",
+			wantEnabled:  []string{"accounts", "Items", "orders"},
+			wantServices: []string{"Items", "orders"},
+		},
+		{
+			name:         "case insensitive names",
+			yaml:         "modules:\n  ITEMS:\n    enabled: false\n  Accounts:\n    enabled: false\n",
+			wantEnabled:  []string{"orders"},
+			wantServices: []string{"orders"},
+		},
+		{
+			name:         "disabled with a string value",
+			yaml:         "modules:\n  items:\n    enabled: \"false\"\n",
+			wantEnabled:  []string{"accounts", "orders"},
+			wantServices: []string{"orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, tt.yaml)
+			for _, m := range modules {
+				a.RegisterModule(m)
+			}
+			cfg := a.Config()
+
+			if got := moduleNames(cfg.Modules()); !reflect.DeepEqual(got, tt.wantEnabled) {
+				t.Errorf("Modules() = %v, want %v", got, tt.wantEnabled)
+			}
+			for _, m := range modules {
+				want := containsString(tt.wantEnabled, m.Name())
+				if got := cfg.ModuleEnabled(strings.ToUpper(m.Name())); got != want {
+					t.Errorf("ModuleEnabled(%s) = %t, want %t", strings.ToUpper(m.Name()), got, want)
+				}
+			}
+			services := ModulesOf[interface{ Services() []string }](cfg)
+			var names []string
+			for _, m := range services {
+				names = append(names, strings.TrimSuffix(m.Services()[0], ".v1"))
+			}
+			if !reflect.DeepEqual(names, tt.wantServices) {
+				t.Errorf("ModulesOf() = %v, want %v", names, tt.wantServices)
+			}
+			if got := moduleNames(a.Modules()); len(got) != len(modules) {
+				t.Errorf("App.Modules() = %v, want every registered module", got)
+			}
+		})
+	}
+}
+
+func TestApp_RegisterModule_config(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantPageSize int
+		wantErr      string
+	}{
+		{name: "default values", wantPageSize: 20},
+		{name: "configured", yaml: "items:\n  page_size: 50\n", wantPageSize: 50},
+		{name: "invalid", yaml: "items:\n  page_size: 500\n", wantErr: "items.page_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, tt.yaml)
+			a.RegisterModule(configModule{testModule{name: "items"}})
+
+			var registered bool
+			for _, d := range a.Configs() {
+				if d.Key == "items" && reflect.TypeOf(d.Prototype) == reflect.TypeOf(itemsConfig{}) {
+					registered = true
+				}
+			}
+			if !registered {
+				t.Fatalf("Configs() = %v, want the items configuration", a.Configs())
+			}
+
+			err := a.ValidateConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ValidateConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateConfig() error = %v", err)
+			}
+			var cfg itemsConfig
+			if err := a.Config().Bind("items", &cfg); err != nil {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			if cfg.PageSize != tt.wantPageSize {
+				t.Errorf("page_size = %d, want %d", cfg.PageSize, tt.wantPageSize)
+			}
+		})
+	}
+}
+
+// TestApp_RegisterModule_duplicate runs itself in a subprocess as registering a module twice exits the process.
+func TestApp_RegisterModule_duplicate(t *testing.T) {
+	if os.Getenv("WIZAPP_TEST_DUPLICATE_MODULE") == "1" {
+		a := New()
+		a.RegisterModule(testModule{name: "items"})
+		a.RegisterModule(testModule{name: "ITEMS"})
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApp_RegisterModule_duplicate$")
+	cmd.Env = append(os.Environ(), "WIZAPP_TEST_DUPLICATE_MODULE=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("duplicate module error = %v, want exit status 1", err)
+	}
+	if want := "Module ITEMS already registered"; !strings.Contains(string(out), want) {
+		t.Errorf("duplicate module output = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	// ReloadAccepted reports a server or component that applied the reloaded configuration.
+	ReloadAccepted ReloadStatus = "ACCEPTED"
+	// ReloadRestartRequired reports a server or component that requires a restart to apply the reloaded configuration.
+	ReloadRestartRequired ReloadStatus = "RESTART_REQUIRED"
+	// ReloadFailed reports a server or component that failed to apply the reloaded configuration.
+	ReloadFailed ReloadStatus = "FAILED"
+	// ReloadNotSupported reports a server or component that does not implement Reloadable.
+	ReloadNotSupported ReloadStatus = "NOT_RELOADABLE"
+)
+
+type (
+	// ReloadStatus is the outcome of a reload for a server or component.
+	ReloadStatus string
+
+	// ReloadResult reports how a server or component handled a reload.
+	ReloadResult struct {
+		Name   string       `json:"name"`
+		Status ReloadStatus `json:"status"`
+		Error  string       `json:"error,omitempty"`
+	}
+
+	// running holds the servers and components of the application being served.
+	running struct {
+		order      []string
+		components map[string]Component
+		supervisor *supervisor
+	}
+)
+
+func (a *App) setRunning(r *running) {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	a.running = r
+}
+
+// Reload reloads the configuration, see ApplicationConfig.Reload, then applies it to the running servers and
+// components implementing Reloadable, in startup order. It returns how each of them handled the change,
+// nothing is reloaded when the application is not served. The secrets of the errors are redacted.
+func (a *App) Reload() ([]ReloadResult, error) {
+	if err := a.config.Reload(); err != nil {
+		return nil, a.config.redactError(err)
+	}
+
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	if a.running == nil {
+		return nil, nil
+	}
+
+	servers := make(map[string]Server)
+	for _, sv := range a.running.supervisor.servers() {
+		servers[sv.name] = sv.server
+	}
+
+	var results []ReloadResult
+	for _, name := range a.running.order {
+		var target interface{}
+		if c, ok := a.running.components[name]; ok {
+			target = c
+		} else if srv, ok := servers[name]; ok {
+			target = srv
+		} else {
+			continue
+		}
+		results = append(results, reload(name, target, a.config))
+	}
+	return results, nil
+}
+
+func reload(name string, target interface{}, cfg *ApplicationConfig) ReloadResult {
+	r, ok := target.(Reloadable)
+	if !ok {
+		return ReloadResult{Name: name, Status: ReloadNotSupported}
+	}
+
+	err := r.Reload(cfg)
+	switch {
+	case err == nil:
+		return ReloadResult{Name: name, Status: ReloadAccepted}
+	case errors.Is(err, ErrRestartRequired):
+		return ReloadResult{Name: name, Status: ReloadRestartRequired, Error: cfg.RedactString(err.Error())}
+	default:
+		return ReloadResult{Name: name, Status: ReloadFailed, Error: cfg.RedactString(err.Error())}
+	}
+}
+
+// reloadOnSignal reloads the application on every SIGHUP until the context is done.
+func (a *App) reloadOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			results, err := a.Reload()
+			if err != nil {
+				log.Printf("configuration not reloaded: %v", err)
+				continue
+			}
+			log.Printf("configuration reloaded")
+			for _, r := range results {
+				if r.Error != "" {
+					log.Printf("reload %s: %s, %s", r.Name, r.Status, r.Error)
+					continue
+				}
+				log.Printf("reload %s: %s", r.Name, r.Status)
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type (
+	reloadableServer struct {
+		*fakeServer
+		err error
+	}
+
+	reloadableComponent struct {
+		*fakeComponent
+		err error
+	}
+
+	poolConfig struct {
+		Mode string `mapstructure:"mode" validate:"oneof=fifo lifo"`
+	}
+)
+
+func (s *reloadableServer) Reload(cfg *ApplicationConfig) error {
+	s.journal.add(fmt.Sprintf("reload %s %s", s.name, cfg.GetString("greeting")))
+	return s.err
+}
+
+func (c *reloadableComponent) Reload(cfg *ApplicationConfig) error {
+	c.journal.add(fmt.Sprintf("reload %s %s", c.name, cfg.GetString("greeting")))
+	return c.err
+}
+
+// serveReloadable serves an application loaded from dir, with reloadable servers and components, and calls
+// reload once the servers are ready.
+func serveReloadable(t *testing.T, dir string, j *journal, reload func(a *App)) {
+	t.Helper()
+	a := New(WithName(t.Name()))
+	a.RegisterConfig("pool", poolConfig{})
+	if err := a.LoadConfig(dir); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	a.RegisterComponent("db", componentFactory(&reloadableComponent{fakeComponent: &fakeComponent{name: "db", journal: j}}))
+	a.RegisterServer("grpc", serverFactory(&reloadableServer{
+		fakeServer: newFakeServer("grpc", j),
+		err:        fmt.Errorf("%w: grpc configuration changed", ErrRestartRequired),
+	}), DependsOn("db"))
+	a.RegisterServer("plain", serverFactory(newFakeServer("plain", j)))
+	a.RegisterServer("worker", serverFactory(&reloadableServer{
+		fakeServer: newFakeServer("worker", j),
+		err:        errors.New("invalid pool size"),
+	}), DependsOn("grpc"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := a.Serve(ctx, nil, OnReady(func() {
+		reload(a)
+		cancel()
+	})); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+}
+
+func TestApp_Reload(t *testing.T) {
+	dir := writeConfig(t, map[string]string{"application.yaml": "greeting: hello\n"})
+	j := &journal{}
+
+	var results []ReloadResult
+	var err error
+	serveReloadable(t, dir, j, func(a *App) {
+		if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte("greeting: bonjour\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		results, err = a.Reload()
+	})
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	want := []ReloadResult{
+		{Name: "db", Status: ReloadAccepted},
+		{Name: "grpc", Status: ReloadRestartRequired, Error: "restart required: grpc configuration changed"},
+		{Name: "plain", Status: ReloadNotSupported},
+		{Name: "worker", Status: ReloadFailed, Error: "invalid pool size"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Reload() = %+v, want %+v", results, want)
+	}
+	// the servers and components are reloaded in startup order, with the reloaded configuration.
+	wantLifecycle := "init db,start grpc,start plain,start worker," +
+		"reload db bonjour,reload grpc bonjour,reload worker bonjour," +
+		"stop worker,stop plain,stop grpc,close db"
+	if got := j.String(); got != wantLifecycle {
+		t.Errorf("lifecycle = %q, want %q", got, wantLifecycle)
+	}
+}
+
+func TestApp_Reload_notApplied(t *testing.T) {
+	tests := []struct {
+		name         string
+		serve        bool
+		config       string
+		wantErr      bool
+		wantGreeting string
+	}{
+		{name: "application not served", config: "greeting: bonjour\n", wantGreeting: "bonjour"},
+		{
+			name:         "invalid configuration",
+			serve:        true,
+			config:       "greeting: bonjour\npool:\n  mode: random\n",
+			wantErr:      true,
+			wantGreeting: "hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{"application.yaml": "greeting: hello\n"})
+			j := &journal{}
+			reload := func(a *App) {
+				if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte(tt.config), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				results, err := a.Reload()
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Reload() error = %v, want error %t", err, tt.wantErr)
+				}
+				if results != nil {
+					t.Errorf("Reload() = %+v, want no results", results)
+				}
+				if got := a.Config().GetString("greeting"); got != tt.wantGreeting {
+					t.Errorf("greeting = %q, want %q", got, tt.wantGreeting)
+				}
+			}
+
+			if tt.serve {
+				serveReloadable(t, dir, j, reload)
+			} else {
+				a := New()
+				if err := a.LoadConfig(dir); err != nil {
+					t.Fatalf("LoadConfig() error = %v", err)
+				}
+				reload(a)
+			}
+			if got := j.String(); strings.Contains(got, "reload") {
+				t.Errorf("lifecycle = %q, want nothing reloaded", got)
+			}
+		})
+	}
+}
+
+func TestApp_reloadOnSignal(t *testing.T) {
+	// the signal does not terminate the test binary while reloadOnSignal registers its own notification.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	dir := writeConfig(t, map[string]string{"application.yaml": "greeting: hello\n"})
+	a := New()
+	if err := a.LoadConfig(dir); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	reloaded := make(chan interface{}, 1)
+	a.Config().OnChange("greeting", func(_, value interface{}) {
+		reloaded <- value
+	})
+	if err := os.WriteFile(filepath.Join(dir, "application.yaml"), []byte("greeting: bonjour\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.reloadOnSignal(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case value := <-reloaded:
+			if value != "bonjour" {
+				t.Errorf("reloaded greeting = %v, want bonjour", value)
+			}
+			return
+		case <-deadline:
+			t.Fatal("configuration not reloaded on SIGHUP")
+		case <-ticker.C:
+		}
+	}
+}
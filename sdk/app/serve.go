@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+)
+
+type (
+	// ServeOption customizes App.Serve.
+	ServeOption func(*serveOptions)
+
+	serveOptions struct {
+		disabled map[string]bool
+		onReady  []func()
+	}
+
+	// registered is a copy of the server registrations of the application, Serve does not hold the application
+	// lock while the servers are created and started.
+	registered struct {
+		serverFactories map[string]ServerFactory
+		registrations   map[string]registration
+	}
+)
+
+// WithoutServers disables the given servers.
+func WithoutServers(names ...string) ServeOption {
+	return func(o *serveOptions) {
+		for _, name := range names {
+			o.disabled[name] = true
+		}
+	}
+}
+
+// OnReady registers a function called once every server is started and ready.
+func OnReady(fn func()) ServeOption {
+	return func(o *serveOptions) {
+		o.onReady = append(o.onReady, fn)
+	}
+}
+
+// registered copies the server registrations of the application.
+func (a *App) registered() registered {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	r := registered{
+		serverFactories: make(map[string]ServerFactory, len(a.serverFactories)),
+		registrations:   make(map[string]registration, len(a.registrations)),
+	}
+	for k, factory := range a.serverFactories {
+		r.serverFactories[k] = factory
+	}
+	for k, reg := range a.registrations {
+		r.registrations[k] = reg
+	}
+	return r
+}
+
+// loadComponents creates the registered components once, they are shared between the CLI commands and Serve.
+// The factories are called without holding the application lock.
+func (a *App) loadComponents(cfg *ApplicationConfig) (map[string]Component, error) {
+	a.componentsOnce.Do(func() {
+		a.mu.RLock()
+		factories := make(map[string]ComponentFactory, len(a.componentFactories))
+		for k, factory := range a.componentFactories {
+			factories[k] = factory
+		}
+		a.mu.RUnlock()
+
+		components := make(map[string]Component)
+		names := make([]string, 0, len(factories))
+		for k := range factories {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			c, err := factories[k](cfg)
+			if err != nil {
+				a.componentsErr = fmt.Errorf("unable to create component %s: %w", k, err)
+				return
+			}
+			components[k] = c
+		}
+		a.components = components
+	})
+	return a.components, a.componentsErr
+}
+
+// Serve runs the setup, then creates and starts the registered servers in dependency order.
+// It blocks until the context is cancelled or a server fails, and stops every server before returning.
+func (a *App) Serve(ctx context.Context, setup Setup, opts ...ServeOption) error {
+	o := &serveOptions{disabled: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := a.loadDefaultConfig(); err != nil {
+		return err
+	}
+
+	cfg := a.Config()
+	if setup != nil {
+		if err := setup(cfg); err != nil {
+			return err
+		}
+	}
+
+	// every problem of the registered configurations is reported before any server is created.
+	if err := a.ValidateConfig(); err != nil {
+		return err
+	}
+	a.registerModuleChecks(cfg)
+
+	watchCfg, err := BindFrom[WatchConfig](cfg, WatchConfigKey)
+	if err != nil {
+		return err
+	}
+	if watchCfg.Enabled {
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		defer stopWatch()
+		if err := cfg.Watch(watchCtx, watchCfg); err != nil {
+			return err
+		}
+	}
+
+	reg := a.registered()
+	components, err := a.loadComponents(cfg)
+	if err != nil {
+		return err
+	}
+
+	startupCfg, err := BindFrom[StartupConfig](cfg, StartupConfigKey)
+	if err != nil {
+		return err
+	}
+
+	shutdownCfg, err := loadShutdownConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	servers := make(map[string]Server)
+	// pending are the servers created but not started yet, they are stopped if the startup fails
+	// to release what their factories acquired (connections, clients).
+	pending := make(map[string]Server)
+	abort := func(err error) error {
+		for name, srv := range pending {
+			discard(name, srv, shutdownCfg)
+		}
+		return err
+	}
+
+	names := make([]string, 0, len(reg.serverFactories))
+	for k := range reg.serverFactories {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		if o.disabled[k] {
+			continue
+		}
+
+		srv, err := reg.serverFactories[k](cfg)
+		if err != nil {
+			return abort(fmt.Errorf("unable to create \"%s\" server: %w", k, err))
+		}
+		servers[k] = srv
+		pending[k] = srv
+	}
+
+	order, err := reg.startupOrder(servers, components)
+	if err != nil {
+		return abort(err)
+	}
+
+	a.states.reset(reg.registrations, servers, o.disabled)
+	sv := newSupervisor(ctx, cfg, reg.registrations, &a.states, startupCfg, shutdownCfg)
+	// initialized are the components in startup order, they are closed once the servers are stopped.
+	var initialized []string
+	for _, name := range order {
+		if c, ok := components[name]; ok {
+			initialized = append(initialized, name)
+			if i, ok := c.(Initializer); ok {
+				if err := i.Init(cfg); err != nil {
+					shutdown(sv, shutdownCfg)
+					err = abort(fmt.Errorf("unable to initialize \"%s\" component: %w", name, err))
+					closeComponents(initialized, components)
+					return err
+				}
+			}
+			continue
+		}
+
+		// launch stops the server itself when it fails to be ready.
+		delete(pending, name)
+		if err := sv.start(name, reg.serverFactories[name], servers[name]); err != nil {
+			shutdown(sv, shutdownCfg)
+			err = abort(err)
+			closeComponents(initialized, components)
+			return err
+		}
+	}
+
+	a.setRunning(&running{order: order, components: components, supervisor: sv})
+
+	for _, fn := range o.onReady {
+		fn()
+	}
+
+	select {
+	case <-ctx.Done():
+		if shutdownCfg.PreStopDelay > 0 {
+			log.Printf("waiting %v before stopping servers", shutdownCfg.PreStopDelay)
+			time.Sleep(shutdownCfg.PreStopDelay)
+		}
+	case err = <-sv.Failures():
+		log.Printf("application stopping. %v", err)
+	}
+	a.setRunning(nil)
+
+	shutdown(sv, shutdownCfg)
+	closeComponents(initialized, components)
+	log.Printf("application stopped")
+
+	return err
+}
+
+// closeComponents closes the components implementing io.Closer in reverse startup order, e.g. the connection
+// pools opened by their Initializer.
+func closeComponents(names []string, components map[string]Component) {
+	for i := len(names) - 1; i >= 0; i-- {
+		c, ok := components[names[i]].(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			log.Printf("error closing %s component: %v", names[i], err)
+		}
+	}
+}
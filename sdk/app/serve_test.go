@@ -0,0 +1,296 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	// fakeServer is a Server recording its lifecycle in a shared journal.
+	fakeServer struct {
+		UnimplementedServer
+		name    string
+		journal *journal
+		// never makes the server never ready.
+		never bool
+		// err is returned by Start once the server is ready.
+		err     error
+		ready   chan struct{}
+		stopped chan struct{}
+		once    sync.Once
+	}
+
+	fakeComponent struct {
+		UnimplementedComponent
+		name    string
+		journal *journal
+		initErr error
+	}
+
+	journal struct {
+		mu      sync.Mutex
+		entries []string
+	}
+)
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) String() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return strings.Join(j.entries, ",")
+}
+
+func newFakeServer(name string, j *journal) *fakeServer {
+	return &fakeServer{name: name, journal: j, ready: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+func (s *fakeServer) Start() error {
+	s.journal.add("start " + s.name)
+	if !s.never {
+		close(s.ready)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeServer) Ready() <-chan struct{} {
+	return s.ready
+}
+
+func (s *fakeServer) Stop(context.Context) error {
+	s.journal.add("stop " + s.name)
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func (c *fakeComponent) Init(*ApplicationConfig) error {
+	c.journal.add("init " + c.name)
+	return c.initErr
+}
+
+func (c *fakeComponent) Close() error {
+	c.journal.add("close " + c.name)
+	return nil
+}
+
+func newTestApp(t *testing.T, yaml string) *App {
+	t.Helper()
+	cfg, err := ParseApplicationConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unable to parse configuration: %v", err)
+	}
+	return New(WithName(t.Name()), WithConfig(cfg))
+}
+
+func serverFactory(srv Server) ServerFactory {
+	return func(*ApplicationConfig) (Server, error) {
+		return srv, nil
+	}
+}
+
+func componentFactory(c Component) ComponentFactory {
+	return func(*ApplicationConfig) (Component, error) {
+		return c, nil
+	}
+}
+
+func TestServe_startup(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		install func(a *App, j *journal)
+		wantErr string
+		want    string
+	}{
+		{
+			name: "components are initialized before the servers",
+			install: func(a *App, j *journal) {
+				a.RegisterServer("a-worker", serverFactory(newFakeServer("a-worker", j)))
+				a.RegisterComponent("z-sql", componentFactory(&fakeComponent{name: "z-sql", journal: j}))
+			},
+			want: "init z-sql,start a-worker,stop a-worker,close z-sql",
+		},
+		{
+			name: "components are closed in reverse startup order once the servers are stopped",
+			install: func(a *App, j *journal) {
+				a.RegisterServer("server", serverFactory(newFakeServer("server", j)), DependsOn("a"))
+				a.RegisterComponent("a", componentFactory(&fakeComponent{name: "a", journal: j}), DependsOn("b"))
+				a.RegisterComponent("b", componentFactory(&fakeComponent{name: "b", journal: j}))
+			},
+			want: "init b,init a,start server,stop server,close a,close b",
+		},
+		{
+			name: "dependencies are started first and stopped last",
+			install: func(a *App, j *journal) {
+				a.RegisterServer("a", serverFactory(newFakeServer("a", j)), DependsOn("b"))
+				a.RegisterServer("b", serverFactory(newFakeServer("b", j)))
+			},
+			want: "start b,start a,stop a,stop b",
+		},
+		{
+			name: "created servers are stopped when a component fails to initialize",
+			install: func(a *App, j *journal) {
+				a.RegisterServer("worker", serverFactory(newFakeServer("worker", j)))
+				a.RegisterComponent("sql", componentFactory(&fakeComponent{name: "sql", journal: j, initErr: errors.New("boom")}))
+			},
+			wantErr: "unable to initialize \"sql\" component: boom",
+			want:    "init sql,stop worker,close sql",
+		},
+		{
+			name: "created servers are stopped when a factory fails",
+			install: func(a *App, j *journal) {
+				a.RegisterServer("a", serverFactory(newFakeServer("a", j)))
+				a.RegisterServer("b", func(*ApplicationConfig) (Server, error) {
+					return nil, errors.New("boom")
+				})
+			},
+			wantErr: "unable to create \"b\" server: boom",
+			want:    "stop a",
+		},
+		{
+			name: "a server not ready within the startup timeout is stopped",
+			config: `
+startup:
+  timeout: 50ms
+`,
+			install: func(a *App, j *journal) {
+				a.RegisterServer("a", serverFactory(newFakeServer("a", j)))
+				srv := newFakeServer("b", j)
+				srv.never = true
+				a.RegisterServer("b", serverFactory(srv), DependsOn("a"))
+			},
+			wantErr: "server b not ready: context deadline exceeded",
+			want:    "start a,start b,stop b,stop a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, tt.config)
+			j := &journal{}
+			tt.install(a, j)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := a.Serve(ctx, nil, OnReady(cancel))
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Serve() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Serve() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := j.String(); got != tt.want {
+				t.Errorf("Serve() lifecycle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServe_readyWaitEndsWithContext(t *testing.T) {
+	a := newTestApp(t, `
+startup:
+  timeout: 0s
+`)
+	j := &journal{}
+	srv := newFakeServer("a", j)
+	srv.never = true
+	a.RegisterServer("a", serverFactory(srv))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Serve(ctx, nil)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Serve() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() still waiting for the server to be ready after the context is done")
+	}
+	if got, want := j.String(), "start a,stop a"; got != want {
+		t.Errorf("Serve() lifecycle = %q, want %q", got, want)
+	}
+}
+
+func TestServe_doesNotHoldTheApplicationLock(t *testing.T) {
+	a := newTestApp(t, "")
+	j := &journal{}
+	a.RegisterServer("a", func(*ApplicationConfig) (Server, error) {
+		// the registration functions take the application lock.
+		a.RegisterCommand(&Command{Name: "late"}, nil)
+		return newFakeServer("a", j), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Serve(ctx, nil, OnReady(cancel))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() blocked registering from a server factory")
+	}
+}
+
+type listeningServer struct {
+	*fakeServer
+	addr net.Addr
+}
+
+func (s *listeningServer) Addr() net.Addr {
+	return s.addr
+}
+
+func TestApp_ServerAddr(t *testing.T) {
+	a := newTestApp(t, "")
+	j := &journal{}
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 43567}
+	a.RegisterServer("grpc", serverFactory(&listeningServer{fakeServer: newFakeServer("grpc", j), addr: addr}))
+	a.RegisterServer("worker", serverFactory(newFakeServer("worker", j)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := map[string]string{}
+	err := a.Serve(ctx, nil, OnReady(func() {
+		got["grpc"] = a.ServerAddr("grpc")
+		got["worker"] = a.ServerAddr("worker")
+		cancel()
+	}))
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	if got["grpc"] != "127.0.0.1:43567" {
+		t.Errorf("ServerAddr(grpc) = %q, want %q", got["grpc"], "127.0.0.1:43567")
+	}
+	if got["worker"] != "" {
+		t.Errorf("ServerAddr(worker) = %q, want none for a server that is not a Listener", got["worker"])
+	}
+	if addr := a.ServerAddr("grpc"); addr != "" {
+		t.Errorf("ServerAddr(grpc) = %q once stopped, want none", addr)
+	}
+}
@@ -1,33 +1,42 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"log"
 )
 
 type (
+	// Server is a long-running process started by the start command.
+	// Start blocks until the server is stopped, Stop must return once the server is stopped or
+	// the context deadline expires.
 	Server interface {
 		Start() error
-		Stop() error
+		Stop(ctx context.Context) error
 		mustImplementServer()
 	}
 
 	ServerFactory func(config *ApplicationConfig) (Server, error)
 )
 
-var (
-	serverFactories = make(map[string]ServerFactory)
-)
+// RegisterServer registers a server factory under the given name in the default application.
+// The options allow declaring the servers or components it depends on, see DependsOn.
+func RegisterServer(name string, factory ServerFactory, opts ...RegisterOption) {
+	defaultApp.RegisterServer(name, factory, opts...)
+}
 
-func RegisterServer(name string, factory ServerFactory) {
-	mu.Lock()
-	defer mu.Unlock()
+// RegisterServer registers a server factory under the given name.
+// The options allow declaring the servers or components it depends on, see DependsOn.
+func (a *App) RegisterServer(name string, factory ServerFactory, opts ...RegisterOption) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	if _, ok := serverFactories[name]; ok {
+	if _, ok := a.serverFactories[name]; ok {
 		log.Fatalf("Server %s already registered", name)
 	}
 
-	serverFactories[name] = factory
+	a.serverFactories[name] = factory
+	a.registrations[name] = newRegistration(opts)
 }
 
 type UnimplementedServer struct{}
@@ -36,7 +45,7 @@ func (u UnimplementedServer) Start() error {
 	return errors.New("method Start not implemented")
 }
 
-func (u UnimplementedServer) Stop() error {
+func (u UnimplementedServer) Stop(context.Context) error {
 	return errors.New("method Stop not implemented")
 }
 
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	ShutdownConfigKey = "shutdown"
+
+	// ExitCodeForced is the exit code used when a second signal forces the application to exit.
+	ExitCodeForced = 130
+)
+
+// ShutdownConfig configures how the servers are stopped.
+//
+//	shutdown:
+//	  pre_stop_delay: 5s  # wait before stopping the servers, e.g. while the load balancer deregisters the instance
+//	  timeout: 30s        # overall deadline to stop every server, 0s for none
+//	  server_timeout: 10s # deadline to stop each server, bounded by the overall deadline
+type ShutdownConfig struct {
+	PreStopDelay  time.Duration `mapstructure:"pre_stop_delay"`
+	Timeout       time.Duration `mapstructure:"timeout" default:"30s"`
+	ServerTimeout time.Duration `mapstructure:"server_timeout"`
+}
+
+var exit = os.Exit
+
+func loadShutdownConfig(cfg *ApplicationConfig) (ShutdownConfig, error) {
+	return BindFrom[ShutdownConfig](cfg, ShutdownConfigKey)
+}
+
+// interruptOnSignal calls stop on the first SIGINT or SIGTERM until done is closed, i.e. once Serve returned.
+// Once the shutdown started, a second signal forces the application to exit immediately.
+func interruptOnSignal(done <-chan struct{}, stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	interrupt(signals, done, stop)
+}
+
+func interrupt(signals <-chan os.Signal, done <-chan struct{}, stop func()) {
+	select {
+	case <-done:
+		return
+	case s := <-signals:
+		log.Printf("application stopping. Signal %v, send it again to force exit", s)
+		stop()
+	}
+
+	select {
+	case <-done:
+	case s := <-signals:
+		log.Printf("application forced to exit. Signal %v", s)
+		exit(ExitCodeForced)
+	}
+}
+
+// shutdown stops every started server within the configured deadlines.
+func shutdown(sv *supervisor, shutdownCfg ShutdownConfig) {
+	ctx, cancel := shutdownContext(shutdownCfg)
+	defer cancel()
+
+	sv.stop(ctx, shutdownCfg.ServerTimeout)
+}
+
+// shutdownContext returns the context of the overall shutdown deadline, when set.
+func shutdownContext(shutdownCfg ShutdownConfig) (context.Context, context.CancelFunc) {
+	if shutdownCfg.Timeout > 0 {
+		return context.WithTimeout(context.Background(), shutdownCfg.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// stopServer stops the server within the context deadline.
+// It returns the context error when the server did not stop in time.
+func stopServer(ctx context.Context, srv Server, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestInterrupt(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  int
+		wantStop bool
+		wantExit bool
+	}{
+		{name: "returns once served without signal"},
+		{name: "first signal stops the application", signals: 1, wantStop: true},
+		{name: "second signal forces the exit", signals: 2, wantStop: true, wantExit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exited []int
+			exit = func(code int) { exited = append(exited, code) }
+			defer func() { exit = os.Exit }()
+
+			signals := make(chan os.Signal, 2)
+			for i := 0; i < tt.signals; i++ {
+				signals <- syscall.SIGTERM
+			}
+			// Serve returns once stopped, unless a second signal is pending.
+			done := make(chan struct{})
+			if tt.signals == 0 {
+				close(done)
+			}
+
+			stopped := false
+			interrupt(signals, done, func() {
+				stopped = true
+				if tt.signals == 1 {
+					close(done)
+				}
+			})
+
+			if stopped != tt.wantStop {
+				t.Errorf("interrupt() stopped = %t, want %t", stopped, tt.wantStop)
+			}
+			if tt.wantExit && (len(exited) != 1 || exited[0] != ExitCodeForced) {
+				t.Errorf("interrupt() exit codes = %v, want [%d]", exited, ExitCodeForced)
+			}
+			if !tt.wantExit && len(exited) > 0 {
+				t.Errorf("interrupt() exit codes = %v, want none", exited)
+			}
+		})
+	}
+}
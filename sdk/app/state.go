@@ -0,0 +1,124 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ServerDisabled   ServerState = "DISABLED"
+	ServerStarting   ServerState = "STARTING"
+	ServerRunning    ServerState = "RUNNING"
+	ServerRestarting ServerState = "RESTARTING"
+	ServerStopping   ServerState = "STOPPING"
+	ServerStopped    ServerState = "STOPPED"
+	ServerFailed     ServerState = "FAILED"
+)
+
+type (
+	// ServerState is the lifecycle state of a registered server.
+	ServerState string
+
+	// ServerStatus reports the state of a registered server.
+	ServerStatus struct {
+		Name      string      `json:"name"`
+		State     ServerState `json:"state"`
+		DependsOn []string    `json:"depends_on,omitempty"`
+		Restarts  int         `json:"restarts"`
+		Addr      string      `json:"addr,omitempty"`
+		Error     string      `json:"error,omitempty"`
+		Since     time.Time   `json:"since"`
+	}
+
+	serverStates struct {
+		mu       sync.RWMutex
+		statuses map[string]*ServerStatus
+	}
+)
+
+// reset registers the servers of a new Serve call, the disabled ones are reported as such.
+func (s *serverStates) reset(registrations map[string]registration, servers map[string]Server, disabled map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses = make(map[string]*ServerStatus)
+	now := time.Now()
+	for name := range servers {
+		s.statuses[name] = &ServerStatus{Name: name, State: ServerStarting, DependsOn: registrations[name].dependsOn, Since: now}
+	}
+	for name := range disabled {
+		if _, ok := registrations[name]; ok {
+			s.statuses[name] = &ServerStatus{Name: name, State: ServerDisabled, DependsOn: registrations[name].dependsOn, Since: now}
+		}
+	}
+}
+
+func (s *serverStates) set(name string, state ServerState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.statuses == nil {
+		s.statuses = make(map[string]*ServerStatus)
+	}
+	st, ok := s.statuses[name]
+	if !ok {
+		st = &ServerStatus{Name: name}
+		s.statuses[name] = st
+	}
+	if state == ServerRestarting {
+		st.Restarts++
+	}
+	st.State = state
+	st.Since = time.Now()
+	st.Addr = ""
+	st.Error = ""
+	if err != nil {
+		st.Error = err.Error()
+	}
+}
+
+// running reports the server as running, with the address it listens on when it is a Listener.
+func (s *serverStates) running(name string, srv Server) {
+	s.set(name, ServerRunning, nil)
+	listener, ok := srv.(Listener)
+	if !ok {
+		return
+	}
+	addr := listener.Addr()
+	if addr == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[name].Addr = addr.String()
+}
+
+// Servers returns the status of the registered servers, sorted by name.
+// The servers that were never started are not reported.
+func (a *App) Servers() []ServerStatus {
+	a.states.mu.RLock()
+	defer a.states.mu.RUnlock()
+
+	statuses := make([]ServerStatus, 0, len(a.states.statuses))
+	for _, st := range a.states.statuses {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// ServerAddr returns the address the named server listens on, or an empty string when the server is not
+// running or is not a Listener.
+func (a *App) ServerAddr(name string) string {
+	a.states.mu.RLock()
+	defer a.states.mu.RUnlock()
+
+	if st, ok := a.states.statuses[name]; ok && st.State == ServerRunning {
+		return st.Addr
+	}
+	return ""
+}
@@ -0,0 +1,324 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// errStopping reports a restart abandoned because the supervisor is stopping.
+var errStopping = errors.New("supervisor stopping")
+
+const (
+	// ExitCodeError is the exit code for any error not related to a server.
+	ExitCodeError = 1
+	// ExitCodeServerFailure is the exit code used when a server failed and the application was shut down.
+	ExitCodeServerFailure = 2
+)
+
+type (
+	// RestartPolicy defines how many times a failed server is recreated and restarted before the failure
+	// is propagated and the application is shut down. The wait between restarts starts at InitialBackoff
+	// and doubles after each restart up to MaxBackoff.
+	RestartPolicy struct {
+		MaxRestarts    int
+		InitialBackoff time.Duration
+		MaxBackoff     time.Duration
+	}
+
+	// ServerError reports the failure of a server.
+	ServerError struct {
+		Server string
+		Err    error
+	}
+
+	// supervisor starts the servers, restarts them according to their RestartPolicy and reports the
+	// failures that must shut down the application.
+	supervisor struct {
+		// ctx is the context of Serve, the servers are no longer awaited once it is done.
+		ctx            context.Context
+		startupTimeout time.Duration
+		shutdownCfg    ShutdownConfig
+		cfg            *ApplicationConfig
+		registrations  map[string]registration
+		states         *serverStates
+		mu             sync.Mutex
+		started        []*supervised
+		failures       chan error
+		stopping       chan struct{}
+		stopOnce       sync.Once
+		// stopCtx and serverTimeout are the deadlines of stop, set before stopping is closed.
+		stopCtx       context.Context
+		serverTimeout time.Duration
+	}
+
+	supervised struct {
+		name    string
+		factory ServerFactory
+		server  Server
+		exited  <-chan error
+	}
+)
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server \"%s\" failed: %v", e.Server, e.Err)
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+// WithRestartPolicy restarts the server according to the given policy when it fails.
+// By default, a failed server shuts down the application.
+func WithRestartPolicy(policy RestartPolicy) RegisterOption {
+	return func(r *registration) {
+		r.restartPolicy = &policy
+	}
+}
+
+// ExitCode returns the process exit code for the error returned by Run.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return ExitCodeServerFailure
+	}
+	return ExitCodeError
+}
+
+func newSupervisor(ctx context.Context, cfg *ApplicationConfig, registrations map[string]registration, states *serverStates,
+	startupCfg StartupConfig, shutdownCfg ShutdownConfig) *supervisor {
+	return &supervisor{
+		ctx:            ctx,
+		startupTimeout: startupCfg.Timeout,
+		shutdownCfg:    shutdownCfg,
+		cfg:            cfg,
+		registrations:  registrations,
+		states:         states,
+		failures:       make(chan error, 1),
+		stopping:       make(chan struct{}),
+	}
+}
+
+// start launches the server and waits until it is ready, then supervises it in the background.
+func (s *supervisor) start(name string, factory ServerFactory, srv Server) error {
+	exited, err := s.launch(name, srv)
+	if err != nil {
+		s.states.set(name, ServerFailed, err)
+		return &ServerError{Server: name, Err: err}
+	}
+	s.states.running(name, srv)
+
+	sv := &supervised{name: name, factory: factory, server: srv, exited: exited}
+	s.mu.Lock()
+	s.started = append(s.started, sv)
+	s.mu.Unlock()
+
+	go s.supervise(sv)
+	return nil
+}
+
+func (s *supervisor) supervise(sv *supervised) {
+	policy := s.registrations[sv.name].restartPolicy
+	restarts := 0
+	var backoff time.Duration
+	if policy != nil {
+		backoff = policy.InitialBackoff
+	}
+
+	for {
+		var err error
+		select {
+		case err = <-sv.exited:
+		case <-s.stopping:
+			return
+		}
+
+		if s.isStopping() {
+			return
+		}
+		if err == nil {
+			err = errors.New("server stopped unexpectedly")
+		}
+		// the error is reported by the server states and the logs.
+		err = s.cfg.redactError(err)
+
+		if policy == nil || restarts >= policy.MaxRestarts {
+			s.states.set(sv.name, ServerFailed, err)
+			s.fail(&ServerError{Server: sv.name, Err: err})
+			return
+		}
+		restarts++
+		s.states.set(sv.name, ServerRestarting, err)
+		log.Printf("server %s failed: %v, restarting in %v (%d/%d)", sv.name, err, backoff, restarts, policy.MaxRestarts)
+
+		select {
+		case <-time.After(backoff):
+		case <-s.stopping:
+			return
+		}
+		if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+
+		// the exited instance releases what its factory acquired, e.g. connections and clients.
+		discard(sv.name, sv.server, s.shutdownCfg)
+		if err := s.restart(sv); errors.Is(err, errStopping) {
+			return
+		} else if err != nil {
+			s.states.set(sv.name, ServerFailed, err)
+			s.fail(&ServerError{Server: sv.name, Err: err})
+			return
+		}
+		s.states.running(sv.name, sv.server)
+	}
+}
+
+// restart recreates the server from its factory and launches it again. When the shutdown starts meanwhile,
+// the new instance is stopped and errStopping is returned, stop only knows the previous instance.
+func (s *supervisor) restart(sv *supervised) error {
+	srv, err := sv.factory(s.cfg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	stopping := s.isStopping()
+	s.mu.Unlock()
+	if stopping {
+		s.stopInstance(sv.name, srv)
+		return errStopping
+	}
+
+	exited, err := s.launch(sv.name, srv)
+	if err != nil {
+		if s.ctx.Err() != nil || s.isStopping() {
+			return errStopping
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isStopping() {
+		s.stopInstance(sv.name, srv)
+		return errStopping
+	}
+	sv.server = srv
+	sv.exited = exited
+	return nil
+}
+
+// stopInstance stops a server instance unknown to stop, within the deadlines of stop.
+func (s *supervisor) stopInstance(name string, srv Server) {
+	if err := stopServer(s.stopCtx, srv, s.serverTimeout); err != nil {
+		log.Printf("error stopping %s server: %v", name, err)
+	}
+}
+
+// servers returns the current instance of the started servers, in the order they were started.
+func (s *supervisor) servers() []*supervised {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	servers := make([]*supervised, 0, len(s.started))
+	for _, sv := range s.started {
+		servers = append(servers, &supervised{name: sv.name, server: sv.server})
+	}
+	return servers
+}
+
+func (s *supervisor) fail(err error) {
+	select {
+	case s.failures <- err:
+	default:
+	}
+}
+
+// Failures receives the first failure that must shut down the application.
+func (s *supervisor) Failures() <-chan error {
+	return s.failures
+}
+
+func (s *supervisor) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop stops the started servers in the reverse order they were started.
+// Each server gets at most serverTimeout to stop, when greater than zero, within the ctx deadline.
+func (s *supervisor) stop(ctx context.Context, serverTimeout time.Duration) {
+	s.stopOnce.Do(func() {
+		s.stopCtx, s.serverTimeout = ctx, serverTimeout
+		close(s.stopping)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.started) - 1; i >= 0; i-- {
+		sv := s.started[i]
+		s.states.set(sv.name, ServerStopping, nil)
+		if err := stopServer(ctx, sv.server, serverTimeout); err != nil {
+			log.Printf("error stopping %s server: %v", sv.name, err)
+			s.states.set(sv.name, ServerStopped, err)
+			continue
+		}
+		s.states.set(sv.name, ServerStopped, nil)
+	}
+}
+
+// launch starts the server in its own goroutine and waits until it is ready, at most the startup timeout
+// and until the context of Serve is done. The returned channel receives the result of Server.Start once it returns.
+// The server is stopped when it fails to be ready.
+func (s *supervisor) launch(name string, srv Server) (<-chan error, error) {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start()
+	}()
+
+	r, ok := srv.(Readiness)
+	if !ok {
+		return errCh, nil
+	}
+
+	ctx, cancel := s.ctx, context.CancelFunc(func() {})
+	if s.startupTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.startupTimeout)
+	}
+	defer cancel()
+
+	select {
+	case <-r.Ready():
+		return errCh, nil
+	case err := <-errCh:
+		if err == nil {
+			err = fmt.Errorf("server %s stopped before being ready", name)
+		}
+		discard(name, srv, s.shutdownCfg)
+		return nil, err
+	case <-ctx.Done():
+		discard(name, srv, s.shutdownCfg)
+		return nil, fmt.Errorf("server %s not ready: %w", name, ctx.Err())
+	case <-s.stopping:
+		discard(name, srv, s.shutdownCfg)
+		return nil, errStopping
+	}
+}
+
+// discard stops a server that is not supervised, e.g. created but not started, within the shutdown deadlines,
+// releasing what its factory acquired.
+func discard(name string, srv Server, shutdownCfg ShutdownConfig) {
+	ctx, cancel := shutdownContext(shutdownCfg)
+	defer cancel()
+	if err := stopServer(ctx, srv, shutdownCfg.ServerTimeout); err != nil {
+		log.Printf("error stopping %s server: %v", name, err)
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyFactory creates servers failing once ready, until the given number of instances failed.
+func flakyFactory(name string, j *journal, failures int) ServerFactory {
+	var mu sync.Mutex
+	instances := 0
+	return func(*ApplicationConfig) (Server, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		instances++
+		srv := newFakeServer(fmt.Sprintf("%s#%d", name, instances), j)
+		if instances <= failures {
+			srv.err = errors.New("boom")
+		}
+		return srv, nil
+	}
+}
+
+func TestSupervisor_restartPolicy(t *testing.T) {
+	policy := func(maxRestarts int) RegisterOption {
+		return WithRestartPolicy(RestartPolicy{MaxRestarts: maxRestarts, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		opts         []RegisterOption
+		wantErr      bool
+		wantRestarts int
+		want         string
+	}{
+		{
+			name:     "a failed server without policy shuts down the application",
+			failures: 1,
+			wantErr:  true,
+			want:     "start worker#1,stop worker#1",
+		},
+		{
+			name:         "a failed server is recreated and restarted",
+			failures:     2,
+			opts:         []RegisterOption{policy(3)},
+			wantRestarts: 2,
+			want:         "start worker#1,stop worker#1,start worker#2,stop worker#2,start worker#3,stop worker#3",
+		},
+		{
+			name:         "the failure is propagated once the restarts are exhausted",
+			failures:     3,
+			opts:         []RegisterOption{policy(2)},
+			wantErr:      true,
+			wantRestarts: 2,
+			want:         "start worker#1,stop worker#1,start worker#2,stop worker#2,start worker#3,stop worker#3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, "")
+			j := &journal{}
+			a.RegisterServer("worker", flakyFactory("worker", j, tt.failures), tt.opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() {
+				done <- a.Serve(ctx, nil)
+			}()
+
+			var status ServerStatus
+			if !tt.wantErr {
+				// the last instance keeps running until the application is stopped.
+				status = waitForState(t, a, "worker", ServerRunning, tt.wantRestarts)
+				cancel()
+			}
+			err := <-done
+
+			if tt.wantErr {
+				var serverErr *ServerError
+				if !errors.As(err, &serverErr) || serverErr.Server != "worker" || ExitCode(err) != ExitCodeServerFailure {
+					t.Fatalf("Serve() error = %v, want a failure of the worker server", err)
+				}
+				status = serverStatus(a, "worker")
+			} else if err != nil {
+				t.Fatalf("Serve() error = %v", err)
+			}
+
+			if status.Restarts != tt.wantRestarts {
+				t.Errorf("Servers() restarts = %d, want %d", status.Restarts, tt.wantRestarts)
+			}
+			if got := j.String(); got != tt.want {
+				t.Errorf("Serve() lifecycle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func serverStatus(a *App, name string) ServerStatus {
+	for _, st := range a.Servers() {
+		if st.Name == name {
+			return st
+		}
+	}
+	return ServerStatus{}
+}
+
+// waitForState waits until the server reaches the state after the given number of restarts.
+func waitForState(t *testing.T, a *App, name string, state ServerState, restarts int) ServerStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := serverStatus(a, name)
+		if st.State == state && st.Restarts == restarts {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server %s is %s after %d restarts, want %s after %d", name, st.State, st.Restarts, state, restarts)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
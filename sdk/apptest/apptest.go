@@ -0,0 +1,169 @@
+// Package apptest boots a wizapp application in-process for integration tests.
+//
+//	func TestItems(t *testing.T) {
+//		instance := apptest.Start(t, `
+//	logger:
+//	  level: debug
+//	`, func(a *app.App) {
+//			grpc_server.Install(a).RegisterServerService(func(srv *grpc.Server) {
+//				itemsV1.RegisterItemServiceServer(srv, service.New())
+//			})
+//			gw := grpc_gateway_server.Install(a)
+//			gw.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials()))
+//			gw.RegisterServiceHandlers(func(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+//				return itemsV1.RegisterItemServiceHandler(context.Background(), mux, conn)
+//			})
+//		})
+//
+//		client := itemsV1.NewItemServiceClient(instance.Conn)
+//		resp, err := http.Get(instance.BaseURL + "/v1/items/1")
+//		...
+//	}
+package apptest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/grpc_server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	host = "127.0.0.1"
+	// gatewayServerName is grpc_gateway_server.ServerName, the package is not imported so that the default
+	// application does not get a gateway registered.
+	gatewayServerName = "grpc-gateway"
+
+	defaultStartTimeout = 30 * time.Second
+)
+
+type (
+	// Instance is a started application.
+	Instance struct {
+		// App is the application under test.
+		App *app.App
+		// Config is the resolved application configuration.
+		Config *app.ApplicationConfig
+		// GRPCAddr is the address the grpc server listens on, empty when it is not running.
+		GRPCAddr string
+		// Conn is a client connection to the grpc server, nil when it is not running.
+		Conn *grpc.ClientConn
+		// BaseURL is the base URL of the grpc-gateway server, e.g. http://127.0.0.1:43567, empty when it is not
+		// running.
+		BaseURL string
+	}
+
+	// Option customizes Start.
+	Option func(*options)
+
+	options struct {
+		setup        app.Setup
+		appOptions   []app.Option
+		serveOptions []app.ServeOption
+		startTimeout time.Duration
+	}
+)
+
+// WithSetup sets the setup function run before the servers are created.
+func WithSetup(setup app.Setup) Option {
+	return func(o *options) {
+		o.setup = setup
+	}
+}
+
+// WithAppOptions adds options to the application under test.
+func WithAppOptions(opts ...app.Option) Option {
+	return func(o *options) {
+		o.appOptions = append(o.appOptions, opts...)
+	}
+}
+
+// WithoutServers disables the given servers.
+func WithoutServers(names ...string) Option {
+	return func(o *options) {
+		o.serveOptions = append(o.serveOptions, app.WithoutServers(names...))
+	}
+}
+
+// WithStartTimeout sets how long to wait for the servers to be ready (default 30s).
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.startTimeout = timeout
+	}
+}
+
+// Start creates an application from the inline yaml configuration, lets install register its servers
+// and components, and starts it. The grpc and grpc-gateway servers listen on ephemeral ports picked by the
+// kernel when they start, their addresses are read back from the application. Start waits until every server is ready and stops the application when the test finishes.
+func Start(t testing.TB, config string, install func(a *app.App), opts ...Option) *Instance {
+	t.Helper()
+
+	o := &options{startTimeout: defaultStartTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cfg, err := app.ParseApplicationConfig([]byte(config))
+	if err != nil {
+		t.Fatalf("unable to parse configuration: %v", err)
+	}
+
+	cfg.Set(grpc_server.ConfigKey+".host", host)
+	cfg.Set(grpc_server.ConfigKey+".port", 0)
+	cfg.Set(grpc_server.ConfigKey+".gateway.host", host)
+	cfg.Set(grpc_server.ConfigKey+".gateway.port", 0)
+
+	a := app.New(append(o.appOptions, app.WithName(t.Name()), app.WithConfig(cfg))...)
+	if install != nil {
+		install(a)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Serve(ctx, o.setup, append(o.serveOptions, app.OnReady(func() {
+			close(ready)
+		}))...)
+	}()
+
+	select {
+	case <-ready:
+	case err := <-done:
+		cancel()
+		t.Fatalf("unable to start application: %v", err)
+	case <-time.After(o.startTimeout):
+		cancel()
+		t.Fatalf("application not ready after %v", o.startTimeout)
+	}
+
+	instance := &Instance{App: a, Config: cfg, GRPCAddr: a.ServerAddr(grpc_server.ServerName)}
+	if addr := a.ServerAddr(gatewayServerName); addr != "" {
+		instance.BaseURL = fmt.Sprintf("http://%s", addr)
+	}
+	if instance.GRPCAddr != "" {
+		conn, err := grpc.Dial(instance.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			cancel()
+			t.Fatalf("unable to connect to grpc server %s: %v", instance.GRPCAddr, err)
+		}
+		instance.Conn = conn
+	}
+
+	t.Cleanup(func() {
+		if instance.Conn != nil {
+			_ = instance.Conn.Close()
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("application stopped with error: %v", err)
+		}
+	})
+
+	return instance
+}
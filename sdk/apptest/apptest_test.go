@@ -0,0 +1,99 @@
+package apptest
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/grpc_server"
+	"github.com/ovargas/wizapp/sdk/grpc_server/grpc_gateway_server"
+	"github.com/ovargas/wizapp/sdk/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// install registers a grpc server and a gateway proxying GET /v1/health to the grpc health service.
+func install(a *app.App) {
+	grpc_server.Install(a)
+	gw := grpc_gateway_server.Install(a)
+	gw.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials()))
+	gw.RegisterServiceHandlers(func(mux *runtime.ServeMux, conn *grpc.ClientConn) error {
+		client := healthpb.NewHealthClient(conn)
+		return mux.HandlePath(http.MethodGet, "/v1/health", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			resp, err := client.Check(r.Context(), &healthpb.HealthCheckRequest{})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			_, _ = io.WriteString(w, resp.Status.String())
+		})
+	})
+}
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d %s", url, resp.StatusCode, body)
+	}
+	return string(body)
+}
+
+func TestStart(t *testing.T) {
+	instance := Start(t, "", install)
+
+	host, port, err := net.SplitHostPort(instance.GRPCAddr)
+	if err != nil || host != "127.0.0.1" || port == "0" {
+		t.Fatalf("Start() GRPCAddr = %q, want an ephemeral port on 127.0.0.1", instance.GRPCAddr)
+	}
+
+	resp, err := healthpb.NewHealthClient(instance.Conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Check() status = %v, want %v", resp.Status, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	get(t, instance.BaseURL+health.LivenessPath)
+	// the gateway reaches the grpc server on the port it is bound to.
+	if got, want := get(t, instance.BaseURL+"/v1/health"), healthpb.HealthCheckResponse_SERVING.String(); got != want {
+		t.Errorf("GET /v1/health = %q, want %q", got, want)
+	}
+}
+
+func TestStart_instancesDoNotShareAddresses(t *testing.T) {
+	first := Start(t, "", install)
+	second := Start(t, "", install)
+
+	if first.GRPCAddr == second.GRPCAddr {
+		t.Errorf("Start() GRPCAddr = %q for both instances", first.GRPCAddr)
+	}
+	if first.BaseURL == second.BaseURL {
+		t.Errorf("Start() BaseURL = %q for both instances", first.BaseURL)
+	}
+}
+
+func TestStart_withoutServers(t *testing.T) {
+	instance := Start(t, "", install, WithoutServers(gatewayServerName))
+
+	if instance.BaseURL != "" {
+		t.Errorf("Start() BaseURL = %q, want none without gateway", instance.BaseURL)
+	}
+	if instance.Conn == nil || instance.GRPCAddr == "" {
+		t.Errorf("Start() grpc connection = %v on %q, want one", instance.Conn, instance.GRPCAddr)
+	}
+}
@@ -1,23 +1,29 @@
 package datasource
 
 import (
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/ovargas/wizapp/sdk/app"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 type (
 	Config struct {
-		ConnectionString      string        `mapstructure:"connection_string"`
-		DriverName            string        `mapstructure:"driver_name"`
-		MaxOpenConnections    int           `mapstructure:"max_open_connections"`
-		MaxIdleConnections    int           `mapstructure:"max_idle_connections"`
-		MaxConnectionLifeTime time.Duration `mapstructure:"max_connection_life_time"`
-		MaxConnectionIdleTime time.Duration `mapstructure:"max_connection_idle_time"`
+		ConnectionString      string        `mapstructure:"connection_string" validate:"required" secret:"true"`
+		DriverName            string        `mapstructure:"driver_name" validate:"required"`
+		MaxOpenConnections    int           `mapstructure:"max_open_connections" validate:"min=0"`
+		MaxIdleConnections    int           `mapstructure:"max_idle_connections" validate:"min=0"`
+		MaxConnectionLifeTime time.Duration `mapstructure:"max_connection_life_time" validate:"min=0s"`
+		MaxConnectionIdleTime time.Duration `mapstructure:"max_connection_idle_time" validate:"min=0s"`
 	}
 
 	Datasource struct {
-		config map[string]Config
+		mu          sync.Mutex
+		config      map[string]Config
+		connections map[string][]*sqlx.DB
 	}
 
 	ErrDataSource string
@@ -56,8 +62,8 @@ const (
 //	    max_connection_life_time: 0s
 //	    max_connection_idle_time: 0s
 func LoadFromConfig(cfg *app.ApplicationConfig) (*Datasource, error) {
-	var dsCfg map[string]Config
-	if err := cfg.UnmarshalKey("datasource", &dsCfg); err != nil {
+	dsCfg, err := app.BindFrom[map[string]Config](cfg, ConfigKey)
+	if err != nil {
 		return nil, err
 	}
 	return Load(dsCfg)
@@ -85,6 +91,9 @@ func LoadWithDefault(config Config) (*Datasource, error) {
 // Creates a sqlx.DB instance for the provided datasource name
 // It can throw the error ErrDataSourceNotConfigured if the provided datasource name is not registered
 func (ds *Datasource) GetConnection(name string) (*sqlx.DB, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
 	c, ok := ds.config[name]
 	if !ok {
 		return nil, ErrDataSourceNotConfigured
@@ -96,12 +105,83 @@ func (ds *Datasource) GetConnection(name string) (*sqlx.DB, error) {
 		return nil, err
 	}
 
+	setPool(db, c)
+
+	if ds.connections == nil {
+		ds.connections = make(map[string][]*sqlx.DB)
+	}
+	ds.connections[name] = append(ds.connections[name], db)
+
+	return db, nil
+}
+
+// Reconfigure
+//
+// Applies the pool settings of the provided configuration to the connections created by GetConnection.
+// The driver and the connection string of an open connection can not be changed, an error wrapping
+// app.ErrRestartRequired is returned when they changed or an open datasource was removed, the other datasources
+// are reconfigured anyway
+func (ds *Datasource) Reconfigure(config map[string]Config) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var changed []string
+	for name, connections := range ds.connections {
+		c, ok := config[name]
+		if !ok || c.DriverName != ds.config[name].DriverName || c.ConnectionString != ds.config[name].ConnectionString {
+			changed = append(changed, name)
+			continue
+		}
+		for _, db := range connections {
+			setPool(db, c)
+		}
+	}
+
+	// the changed datasources keep their configuration until the restart, new connections match the open ones.
+	next := make(map[string]Config, len(config))
+	for name, c := range config {
+		next[name] = c
+	}
+	for _, name := range changed {
+		next[name] = ds.config[name]
+	}
+	ds.config = next
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return fmt.Errorf("%w: datasource %s changed", app.ErrRestartRequired, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// Close
+//
+// Closes the connections created by GetConnection
+func (ds *Datasource) Close() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var errs []string
+	for _, connections := range ds.connections {
+		for _, db := range connections {
+			if err := db.Close(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	ds.connections = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to close datasource connections: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func setPool(db *sqlx.DB, c Config) {
 	db.SetMaxOpenConns(c.MaxOpenConnections)
 	db.SetConnMaxLifetime(c.MaxConnectionLifeTime)
 	db.SetMaxIdleConns(c.MaxIdleConnections)
 	db.SetConnMaxIdleTime(c.MaxConnectionIdleTime)
-
-	return db, nil
 }
 
 // GetDefaultConnection
@@ -0,0 +1,103 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/health"
+)
+
+const (
+	ComponentName = "datasource"
+	ConfigKey     = "datasource"
+	// ResourceName is the name of the Datasource resource of the commands, see FromCommand.
+	ResourceName = "datasource"
+)
+
+type component struct {
+	app.UnimplementedComponent
+	health *health.Registry
+	ds     *Datasource
+}
+
+func init() {
+	app.RegisterResource(ResourceName, createResource)
+	app.RegisterConfig(ConfigKey, map[string]Config{})
+}
+
+// Install registers the Datasource resource and the datasource configuration in the application, they are
+// registered in the default application when the package is imported.
+func Install(a *app.App) {
+	a.RegisterResource(ResourceName, createResource)
+	a.RegisterConfig(ConfigKey, map[string]Config{})
+}
+
+// InstallHealthChecks registers the component opening the configured datasources when the application starts and
+// registering their readiness checks in the application health registry, see HealthCheck.
+//
+//	datasource.InstallHealthChecks(app.Default())
+func InstallHealthChecks(a *app.App) {
+	a.RegisterComponent(ComponentName, func(*app.ApplicationConfig) (app.Component, error) {
+		return &component{health: a.Health()}, nil
+	})
+}
+
+// FromCommand returns the Datasource of the command, it is created on first use and its connections are closed
+// once the command completes.
+func FromCommand(ctx *app.CommandContext) (*Datasource, error) {
+	return app.Resource[*Datasource](ctx, ResourceName)
+}
+
+func createResource(cfg *app.ApplicationConfig) (interface{}, error) {
+	return LoadFromConfig(cfg)
+}
+
+// Init registers a readiness check for every configured datasource.
+func (c *component) Init(cfg *app.ApplicationConfig) error {
+	ds, err := LoadFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	for name := range ds.config {
+		db, err := ds.GetConnection(name)
+		if err != nil {
+			return err
+		}
+		c.health.Register(HealthCheck(name, db))
+	}
+	c.ds = ds
+	return nil
+}
+
+// Close closes the connections opened by Init.
+func (c *component) Close() error {
+	if c.ds == nil {
+		return nil
+	}
+	return c.ds.Close()
+}
+
+// Reload applies the pool settings of the reloaded configuration to the connections of the health checks.
+func (c *component) Reload(cfg *app.ApplicationConfig) error {
+	if c.ds == nil {
+		return nil
+	}
+	config, err := app.BindFrom[map[string]Config](cfg, ConfigKey)
+	if err != nil {
+		return err
+	}
+	return c.ds.Reconfigure(config)
+}
+
+// HealthCheck creates a readiness check pinging the database.
+func HealthCheck(name string, db *sqlx.DB) health.Check {
+	return health.Check{
+		Name: fmt.Sprintf("datasource:%s", name),
+		Kind: health.Readiness,
+		Check: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
@@ -6,14 +6,17 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/ovargas/wizapp/sdk/app"
 	"github.com/ovargas/wizapp/sdk/grpc_server"
+	"github.com/ovargas/wizapp/sdk/health"
 	"github.com/ovargas/wizapp/sdk/logger"
 	"google.golang.org/grpc"
+	"net"
 	"net/http"
+	"sync"
 )
 
 var (
-	log                    = logger.Log()
-	registerServiceHandler []func(mux *runtime.ServeMux, conn *grpc.ClientConn) error
+	log             = logger.Log()
+	defaultRegistry = NewRegistry()
 )
 
 const (
@@ -28,85 +31,245 @@ type (
 
 	Gateway struct {
 		Host string `mapstructure:"host"`
-		Port int    `mapstructure:"port"`
+		Port int    `mapstructure:"port" validate:"max=65535"`
+	}
+
+	// HandlerModule is an app.Module declaring gateway handlers, they are registered in the gateway when the module
+	// is enabled.
+	HandlerModule interface {
+		app.Module
+		RegisterHandlers(mux *runtime.ServeMux, conn *grpc.ClientConn) error
+	}
+
+	// Registry holds the options and handlers used to create the gateway server of an application.
+	Registry struct {
+		mu                     sync.Mutex
+		registerServiceHandler []func(mux *runtime.ServeMux, conn *grpc.ClientConn) error
+		dialOptions            []grpc.DialOption
+		serveMuxOption         []runtime.ServeMuxOption
+		health                 *health.Registry
+		application            *app.App
 	}
 
 	server struct {
 		app.UnimplementedServer
-		server    *http.Server
-		isStarted bool
+		config      Config
+		application *app.App
+		server      *http.Server
+		mux         *runtime.ServeMux
+		handlers    []func(mux *runtime.ServeMux, conn *grpc.ClientConn) error
+		modules     []HandlerModule
+		dialOptions []grpc.DialOption
+		mu          sync.Mutex
+		connection  *grpc.ClientConn
+		isStarted   bool
+		// stopping is set by Stop, a server stopped while it connects to the grpc server does not serve.
+		stopping bool
+		ready    chan struct{}
+		addr     net.Addr
 	}
 )
 
-var (
-	dialOptions    []grpc.DialOption
-	serveMuxOption []runtime.ServeMuxOption
-)
-
 func init() {
-	app.RegisterServer(ServerName, createServer)
+	app.RegisterServer(ServerName, defaultRegistry.CreateServer, app.DependsOn(grpc_server.ServerName))
+	app.RegisterConfig(grpc_server.ConfigKey, Config{})
 }
 
+// NewRegistry creates an empty Registry serving the checks of the default health registry.
+// The gateway connects to the grpc server of the default application.
+func NewRegistry() *Registry {
+	return &Registry{health: health.Default(), application: app.Default()}
+}
+
+// Install registers a gateway server in the application and returns the Registry used to create it.
+// The server serves the checks of the application health registry.
+func Install(a *app.App) *Registry {
+	r := &Registry{health: a.Health(), application: a}
+	a.RegisterServer(ServerName, r.CreateServer, app.DependsOn(grpc_server.ServerName))
+	a.RegisterConfig(grpc_server.ConfigKey, Config{})
+	return r
+}
+
+// RegisterServiceHandlers registers handlers in the gateway of the default application.
 func RegisterServiceHandlers(fn ...func(mux *runtime.ServeMux, conn *grpc.ClientConn) error) {
-	registerServiceHandler = append(registerServiceHandler, fn...)
+	defaultRegistry.RegisterServiceHandlers(fn...)
 }
 
+// WithDialOption adds options to the gateway connection of the default application.
 func WithDialOption(options ...grpc.DialOption) {
-	dialOptions = append(dialOptions, options...)
+	defaultRegistry.WithDialOption(options...)
 }
 
+// WithServeMuxOption adds options to the gateway mux of the default application.
 func WithServeMuxOption(options ...runtime.ServeMuxOption) {
-	serveMuxOption = append(serveMuxOption, options...)
+	defaultRegistry.WithServeMuxOption(options...)
 }
 
-func createServer(config *app.ApplicationConfig) (app.Server, error) {
-	var gwCfg Config
-	if err := config.UnmarshalKey(grpc_server.ConfigKey, &gwCfg); err != nil {
-		return nil, err
-	}
+// RegisterServiceHandlers registers handlers in the gateway.
+func (r *Registry) RegisterServiceHandlers(fn ...func(mux *runtime.ServeMux, conn *grpc.ClientConn) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registerServiceHandler = append(r.registerServiceHandler, fn...)
+}
+
+// WithDialOption adds options to the connection from the gateway to the grpc server.
+func (r *Registry) WithDialOption(options ...grpc.DialOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dialOptions = append(r.dialOptions, options...)
+}
+
+// WithServeMuxOption adds options to the gateway mux.
+func (r *Registry) WithServeMuxOption(options ...runtime.ServeMuxOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serveMuxOption = append(r.serveMuxOption, options...)
+}
 
-	connection, err := grpc.Dial(fmt.Sprintf("%s:%d", gwCfg.Host, gwCfg.Port), dialOptions...)
+// SetHealthRegistry sets the health checks served on the health.LivenessPath and health.ReadinessPath endpoints.
+func (r *Registry) SetHealthRegistry(registry *health.Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health = registry
+}
 
+// CreateServer is the app.ServerFactory of the gateway server.
+func (r *Registry) CreateServer(config *app.ApplicationConfig) (app.Server, error) {
+	gwCfg, err := app.BindFrom[Config](config, grpc_server.ConfigKey)
 	if err != nil {
 		return nil, err
 	}
 
-	mux := runtime.NewServeMux(serveMuxOption...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	for _, register := range registerServiceHandler {
-		if err := register(mux, connection); err != nil {
-			return nil, err
-		}
-	}
+	mux := runtime.NewServeMux(r.serveMuxOption...)
 	httpServer := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", gwCfg.Gateway.Host, gwCfg.Gateway.Port),
-		Handler: mux,
+		Handler: r.health.Mux(mux),
 	}
 
+	// the handlers are registered once the gateway is connected to the grpc server, see Start.
 	return &server{
-		server: httpServer,
+		config:      gwCfg,
+		application: r.application,
+		server:      httpServer,
+		mux:         mux,
+		handlers:    append([]func(mux *runtime.ServeMux, conn *grpc.ClientConn) error(nil), r.registerServiceHandler...),
+		modules:     app.ModulesOf[HandlerModule](config),
+		dialOptions: append([]grpc.DialOption(nil), r.dialOptions...),
+		ready:       make(chan struct{}),
 	}, nil
 }
 
+// Reload requires a restart when the gateway or the grpc listener configuration changed.
+func (s *server) Reload(config *app.ApplicationConfig) error {
+	cfg, err := app.BindFrom[Config](config, grpc_server.ConfigKey)
+	if err != nil {
+		return err
+	}
+	if cfg != s.config {
+		return fmt.Errorf("%w: %s configuration changed", app.ErrRestartRequired, grpc_server.ConfigKey)
+	}
+	return nil
+}
+
+// Start connects to the grpc server, registers the handlers and serves them. The grpc server is started first,
+// when it listens on an ephemeral port (port 0) the gateway connects to the address it is bound to.
 func (s *server) Start() error {
+	s.mu.Lock()
 	if s.isStarted {
+		s.mu.Unlock()
+		return nil
+	}
+	s.isStarted = true
+	s.mu.Unlock()
+
+	if err := s.connect(); err == http.ErrServerClosed {
 		return nil
+	} else if err != nil {
+		log.Errorf("error starting gateway server: %v", err)
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		log.Errorf("error starting gateway server: %v", err)
+		return err
 	}
+	s.addr = listener.Addr()
+	close(s.ready)
 
-	if err := s.server.ListenAndServe(); err != nil {
+	if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 		log.Errorf("error starting gateway server: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (s *server) Stop() error {
-	if !s.isStarted {
-		return nil
+func (s *server) connect() error {
+	target := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	if s.config.Port == 0 {
+		if target = s.application.ServerAddr(grpc_server.ServerName); target == "" {
+			return fmt.Errorf("%s server is not listening", grpc_server.ServerName)
+		}
+	}
+
+	connection, err := grpc.Dial(target, s.dialOptions...)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		_ = connection.Close()
+		return http.ErrServerClosed
+	}
+	s.connection = connection
+	s.mu.Unlock()
+
+	for _, register := range s.handlers {
+		if err := register(s.mux, connection); err != nil {
+			return err
+		}
+	}
+	for _, m := range s.modules {
+		if err := m.RegisterHandlers(s.mux, connection); err != nil {
+			return fmt.Errorf("unable to register the handlers of module %s: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Ready is closed once the server is listening.
+func (s *server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr is the address the server listens on once it is ready.
+func (s *server) Addr() net.Addr {
+	return s.addr
+}
+
+// Stop gracefully stops the server, open connections are closed when the context deadline expires.
+func (s *server) Stop(ctx context.Context) error {
+	// the connection to the grpc server is closed even if the server failed to start.
+	s.mu.Lock()
+	connection, started := s.connection, s.isStarted
+	s.stopping = true
+	s.mu.Unlock()
+	if connection != nil {
+		defer connection.Close()
+	}
+
+	// a server stopped before it serves returns http.ErrServerClosed at once.
+	if !started {
+		return s.server.Close()
 	}
 
-	if err := s.server.Shutdown(context.TODO()); err != nil {
+	if err := s.server.Shutdown(ctx); err != nil {
 		log.Errorf("error stopping gateway server: %v", err)
+		return s.server.Close()
 	}
 	return nil
 }
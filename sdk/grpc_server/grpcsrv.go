@@ -1,11 +1,15 @@
 package grpc_server
 
 import (
+	"context"
 	"fmt"
 	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/health"
 	"github.com/ovargas/wizapp/sdk/logger"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"net"
+	"sync"
 )
 
 const (
@@ -16,86 +20,198 @@ const (
 type (
 	server struct {
 		app.UnimplementedServer
+		mu        sync.Mutex
 		isStarted bool
-		server    *grpc.Server
-		config    *Config
+		// stopping is set by Stop, a server stopped while it starts does not serve.
+		stopping bool
+		ready    chan struct{}
+		addr     net.Addr
+		server   *grpc.Server
+		config   *Config
 	}
 
 	Config struct {
 		Host string `mapstructure:"host"`
-		Port int    `mapstructure:"port"`
+		Port int    `mapstructure:"port" validate:"max=65535"`
+	}
+
+	// ServiceModule is an app.Module declaring grpc services, they are registered in the grpc server when the module
+	// is enabled.
+	ServiceModule interface {
+		app.Module
+		RegisterServices(srv *grpc.Server)
+	}
+
+	// Registry holds the options and services used to create the grpc server of an application.
+	Registry struct {
+		mu                             sync.Mutex
+		serverOptions                  []grpc.ServerOption
+		registerServerServiceFunctions []func(srv *grpc.Server)
+		health                         *health.Registry
 	}
 )
 
 var (
-	log                            = logger.Log()
-	serverOptions                  []grpc.ServerOption
-	registerServerServiceFunctions []func(srv *grpc.Server)
+	log             = logger.Log()
+	defaultRegistry = NewRegistry()
 )
 
 func init() {
-	app.RegisterServer(ServerName, createServer)
+	app.RegisterServer(ServerName, defaultRegistry.CreateServer)
+	app.RegisterConfig(ConfigKey, Config{})
+}
+
+// NewRegistry creates an empty Registry serving the checks of the default health registry.
+func NewRegistry() *Registry {
+	return &Registry{health: health.Default()}
+}
+
+// Install registers a grpc server in the application and returns the Registry used to create it.
+// The server serves the checks of the application health registry.
+func Install(a *app.App) *Registry {
+	r := &Registry{health: a.Health()}
+	a.RegisterServer(ServerName, r.CreateServer)
+	a.RegisterConfig(ConfigKey, Config{})
+	return r
 }
 
+// WithServerOption adds options to the grpc server of the default application.
 func WithServerOption(options ...grpc.ServerOption) {
-	serverOptions = append(serverOptions, options...)
+	defaultRegistry.WithServerOption(options...)
 }
 
+// RegisterServerService registers services in the grpc server of the default application.
 func RegisterServerService(fn ...func(srv *grpc.Server)) {
-	registerServerServiceFunctions = append(registerServerServiceFunctions, fn...)
+	defaultRegistry.RegisterServerService(fn...)
+}
+
+// WithServerOption adds options to the grpc server.
+func (r *Registry) WithServerOption(options ...grpc.ServerOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serverOptions = append(r.serverOptions, options...)
 }
 
-func createServer(config *app.ApplicationConfig) (app.Server, error) {
-	var grpcCfg Config
-	if err := config.UnmarshalKey(ConfigKey, &grpcCfg); err != nil {
+// RegisterServerService registers services in the grpc server.
+func (r *Registry) RegisterServerService(fn ...func(srv *grpc.Server)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registerServerServiceFunctions = append(r.registerServerServiceFunctions, fn...)
+}
+
+// SetHealthRegistry sets the health checks served through grpc.health.v1.Health.
+func (r *Registry) SetHealthRegistry(registry *health.Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health = registry
+}
+
+// CreateServer is the app.ServerFactory of the grpc server.
+func (r *Registry) CreateServer(config *app.ApplicationConfig) (app.Server, error) {
+	grpcCfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
 		return nil, err
 	}
-	s := grpc.NewServer(serverOptions...)
 
-	for _, fn := range registerServerServiceFunctions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := grpc.NewServer(r.serverOptions...)
+	healthpb.RegisterHealthServer(s, health.NewGRPCServer(r.health))
+
+	for _, fn := range r.registerServerServiceFunctions {
 		fn(s)
 	}
+	for _, m := range app.ModulesOf[ServiceModule](config) {
+		m.RegisterServices(s)
+	}
 
 	return &server{
 		config: &grpcCfg,
 		server: s,
+		ready:  make(chan struct{}),
 	}, nil
 }
 
+// Reload requires a restart when the listener configuration changed.
+func (s *server) Reload(config *app.ApplicationConfig) error {
+	cfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
+		return err
+	}
+	if cfg != *s.config {
+		return fmt.Errorf("%w: %s configuration changed", app.ErrRestartRequired, ConfigKey)
+	}
+	return nil
+}
+
 func (s *server) Start() error {
-	if s.isStarted {
+	s.mu.Lock()
+	if s.isStarted || s.stopping {
+		s.mu.Unlock()
 		return nil
 	}
 	s.isStarted = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.isStarted = false
+		s.mu.Unlock()
+	}()
 
-	log.Infof("starting listener", s.config.Host, s.config.Port)
+	log.Infof("starting listener %s:%d", s.config.Host, s.config.Port)
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.config.Host, s.config.Port))
 	if err != nil {
-		log.Fatalf("unable to start listener %s:%d, %v", s.config.Host, s.config.Port, err)
+		return fmt.Errorf("unable to start listener %s:%d: %w", s.config.Host, s.config.Port, err)
 	}
 
-	defer func(listener net.Listener) {
-		err := listener.Close()
-		if err != nil {
-			log.Fatalf("Unable to close listener %s:%d, %v", s.config.Host, s.config.Port, err)
-		}
-	}(listener)
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+	if stopping {
+		return listener.Close()
+	}
 
-	defer func() {
-		s.isStarted = false
-	}()
+	s.addr = listener.Addr()
+	close(s.ready)
 
-	if err := s.server.Serve(listener); err != nil {
-		log.Fatalf("Unable to serve grpc in listener %s:%d, %v", s.config.Host, s.config.Port, err)
+	// Serve returns grpc.ErrServerStopped when Stop is called before it serves.
+	if err := s.server.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+		return fmt.Errorf("unable to serve grpc in listener %s:%d: %w", s.config.Host, s.config.Port, err)
 	}
 
 	return nil
 }
 
-func (s *server) Stop() error {
-	if s.isStarted {
+// Ready is closed once the server is listening.
+func (s *server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr is the address the server listens on once it is ready.
+func (s *server) Addr() net.Addr {
+	return s.addr
+}
+
+// Stop gracefully stops the server, pending RPCs are cancelled when the context deadline expires.
+// A server stopped before it serves does not serve once started.
+func (s *server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
 		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
 		log.Infof("grpc server stopped")
+	case <-ctx.Done():
+		s.server.Stop()
+		log.Warnf("grpc server forced to stop: %v", ctx.Err())
 	}
 	return nil
 }
@@ -0,0 +1,65 @@
+package grpc_server
+
+import (
+	"context"
+	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/health"
+	"testing"
+	"time"
+)
+
+func TestInstall_usesTheApplicationHealthRegistry(t *testing.T) {
+	registry := health.NewRegistry()
+	a := app.New(app.WithHealthRegistry(registry))
+
+	if r := Install(a); r.health != registry {
+		t.Errorf("Install() health registry = %p, want the application one %p", r.health, registry)
+	}
+}
+
+func TestServer_Stop(t *testing.T) {
+	tests := []struct {
+		name      string
+		stopFirst bool
+	}{
+		{name: "stopped before it starts", stopFirst: true},
+		{name: "stopped once ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := app.ParseApplicationConfig([]byte("grpc:\n  host: 127.0.0.1\n  port: 0\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv, err := NewRegistry().CreateServer(cfg)
+			if err != nil {
+				t.Fatalf("CreateServer() error = %v", err)
+			}
+			if tt.stopFirst {
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.Start()
+			}()
+			if !tt.stopFirst {
+				<-srv.(*server).Ready()
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Start() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start() still serving once stopped")
+			}
+		})
+	}
+}
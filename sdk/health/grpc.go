@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// LivenessService is the grpc health service name reporting the liveness checks.
+	// The empty service name reports the readiness checks, any other name the check with that name.
+	LivenessService = "liveness"
+
+	watchInterval = 5 * time.Second
+)
+
+type grpcServer struct {
+	healthpb.UnimplementedHealthServer
+	registry *Registry
+}
+
+// NewGRPCServer creates a grpc.health.v1.Health server backed by the registry.
+func NewGRPCServer(r *Registry) healthpb.HealthServer {
+	return &grpcServer{registry: r}
+}
+
+func (s *grpcServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := s.status(ctx, req.Service)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+func (s *grpcServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		st, ok := s.status(stream.Context(), req.Service)
+		if !ok {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *grpcServer) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	var st Status
+	switch service {
+	case "":
+		st = s.registry.Run(ctx, Readiness).Status
+	case LivenessService:
+		st = s.registry.Run(ctx, Liveness).Status
+	default:
+		res, ok := s.registry.RunCheck(ctx, service)
+		if !ok {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+		}
+		st = res.Status
+	}
+
+	if st == StatusUp {
+		return healthpb.HealthCheckResponse_SERVING, true
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING, true
+}
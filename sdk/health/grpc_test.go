@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCServer_Check(t *testing.T) {
+	srv := NewGRPCServer(newTestRegistry(
+		Check{Name: "loop", Kind: Liveness, Check: up},
+		Check{Name: "db", Kind: Readiness, Check: down},
+		Check{Name: "cache", Kind: Readiness, Check: up},
+	))
+
+	tests := []struct {
+		service  string
+		want     healthpb.HealthCheckResponse_ServingStatus
+		wantCode codes.Code
+	}{
+		{service: "", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: LivenessService, want: healthpb.HealthCheckResponse_SERVING},
+		{service: "cache", want: healthpb.HealthCheckResponse_SERVING},
+		{service: "db", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "unknown", wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			resp, err := srv.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("Check(%q) error = %v, want code %s", tt.service, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check(%q) error = %v", tt.service, err)
+			}
+			if resp.Status != tt.want {
+				t.Errorf("Check(%q) = %s, want %s", tt.service, resp.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of the checks registered without one.
+	DefaultTimeout = 5 * time.Second
+)
+
+const (
+	// Liveness checks report whether the application must be restarted.
+	Liveness Kind = iota
+	// Readiness checks report whether the application is able to serve traffic.
+	// The readiness of the application also requires every liveness check to pass.
+	Readiness
+)
+
+const (
+	StatusUp   Status = "UP"
+	StatusDown Status = "DOWN"
+)
+
+type (
+	// Kind classifies a check as liveness or readiness.
+	Kind int
+
+	// Status of a check or of the whole application.
+	Status string
+
+	// CheckFunc returns an error when the checked resource is not healthy.
+	CheckFunc func(ctx context.Context) error
+
+	// Check is a named health check.
+	Check struct {
+		Name    string
+		Kind    Kind
+		Timeout time.Duration
+		Check   CheckFunc
+	}
+
+	// Result is the outcome of a single check.
+	Result struct {
+		Name     string        `json:"name"`
+		Status   Status        `json:"status"`
+		Error    string        `json:"error,omitempty"`
+		Duration time.Duration `json:"duration"`
+	}
+
+	// Report is the outcome of the checks of a kind.
+	Report struct {
+		Status Status   `json:"status"`
+		Checks []Result `json:"checks"`
+	}
+
+	// Registry holds the health checks of an application.
+	Registry struct {
+		mu     sync.RWMutex
+		checks map[string]Check
+	}
+)
+
+var defaultRegistry = NewRegistry()
+
+func (k Kind) String() string {
+	switch k {
+	case Liveness:
+		return "liveness"
+	case Readiness:
+		return "readiness"
+	}
+	return "unknown"
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Default returns the registry of the default application.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register registers checks in the default registry.
+func Register(checks ...Check) {
+	defaultRegistry.Register(checks...)
+}
+
+// Register registers checks, replacing any previous check with the same name.
+func (r *Registry) Register(checks ...Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range checks {
+		if c.Timeout <= 0 {
+			c.Timeout = DefaultTimeout
+		}
+		r.checks[c.Name] = c
+	}
+}
+
+// Unregister removes the check with the given name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Run runs concurrently the checks required by the kind.
+func (r *Registry) Run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	var checks []Check
+	for _, c := range r.checks {
+		if c.Kind <= kind {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, res := range results {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// RunCheck runs the check with the given name.
+// It returns false when no check is registered with that name.
+func (r *Registry) RunCheck(ctx context.Context, name string) (Result, bool) {
+	r.mu.RLock()
+	c, ok := r.checks[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return Result{}, false
+	}
+	return run(ctx, c), true
+}
+
+func run(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Name: c.Name, Status: StatusUp, Duration: time.Since(start)}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
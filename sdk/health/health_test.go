@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("unreachable") }
+
+func newTestRegistry(checks ...Check) *Registry {
+	r := NewRegistry()
+	r.Register(checks...)
+	return r
+}
+
+func TestRegistry_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		kind       Kind
+		wantStatus Status
+		wantChecks []string
+		wantErr    map[string]string
+	}{
+		{
+			name:       "no checks",
+			kind:       Readiness,
+			wantStatus: StatusUp,
+		},
+		{
+			name: "liveness runs the liveness checks only",
+			checks: []Check{
+				{Name: "loop", Kind: Liveness, Check: up},
+				{Name: "db", Kind: Readiness, Check: down},
+			},
+			kind:       Liveness,
+			wantStatus: StatusUp,
+			wantChecks: []string{"loop"},
+		},
+		{
+			name: "readiness includes the liveness checks",
+			checks: []Check{
+				{Name: "loop", Kind: Liveness, Check: down},
+				{Name: "db", Kind: Readiness, Check: up},
+			},
+			kind:       Readiness,
+			wantStatus: StatusDown,
+			wantChecks: []string{"db", "loop"},
+			wantErr:    map[string]string{"loop": "unreachable"},
+		},
+		{
+			name: "check timeout",
+			checks: []Check{
+				{Name: "slow", Kind: Readiness, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+					// the check ignores its context, the result does not wait for it.
+					time.Sleep(300 * time.Millisecond)
+					return nil
+				}},
+				{Name: "db", Kind: Readiness, Check: up},
+			},
+			kind:       Readiness,
+			wantStatus: StatusDown,
+			wantChecks: []string{"db", "slow"},
+			wantErr:    map[string]string{"slow": context.DeadlineExceeded.Error()},
+		},
+		{
+			name: "panicking check",
+			checks: []Check{
+				{Name: "broken", Kind: Liveness, Check: func(context.Context) error { panic("boom") }},
+			},
+			kind:       Liveness,
+			wantStatus: StatusDown,
+			wantChecks: []string{"broken"},
+			wantErr:    map[string]string{"broken": "check panicked: boom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			report := newTestRegistry(tt.checks...).Run(context.Background(), tt.kind)
+			if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+				t.Errorf("Run() took %v", elapsed)
+			}
+
+			if report.Status != tt.wantStatus {
+				t.Errorf("Run() status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			var names []string
+			for _, res := range report.Checks {
+				names = append(names, res.Name)
+				if want := tt.wantErr[res.Name]; res.Error != want {
+					t.Errorf("check %s error = %q, want %q", res.Name, res.Error, want)
+				}
+				wantStatus := StatusUp
+				if tt.wantErr[res.Name] != "" {
+					wantStatus = StatusDown
+				}
+				if res.Status != wantStatus {
+					t.Errorf("check %s status = %s, want %s", res.Name, res.Status, wantStatus)
+				}
+			}
+			if got, want := strings.Join(names, ","), strings.Join(tt.wantChecks, ","); got != want {
+				t.Errorf("Run() checks = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := newTestRegistry(Check{Name: "db", Check: down})
+	r.Register(Check{Name: "db", Check: up})
+
+	res, ok := r.RunCheck(context.Background(), "db")
+	if !ok || res.Status != StatusUp {
+		t.Errorf("RunCheck() = %v, %t, want the replacing check up", res, ok)
+	}
+
+	r.Unregister("db")
+	if _, ok := r.RunCheck(context.Background(), "db"); ok {
+		t.Error("RunCheck() found an unregistered check")
+	}
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Handler serves the report of the checks of the kind as JSON.
+// The response status is 200 when every check is up, 503 otherwise.
+func (r *Registry) Handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), kind)
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Mux serves the liveness and readiness endpoints and delegates any other request to next.
+func (r *Registry) Mux(next http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, r.Handler(Liveness))
+	mux.Handle(ReadinessPath, r.Handler(Readiness))
+	mux.Handle("/", next)
+	return mux
+}
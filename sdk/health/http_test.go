@@ -0,0 +1,48 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_Mux(t *testing.T) {
+	r := newTestRegistry(
+		Check{Name: "loop", Kind: Liveness, Check: up},
+		Check{Name: "db", Kind: Readiness, Check: down},
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		path       string
+		wantCode   int
+		wantStatus Status
+	}{
+		{path: LivenessPath, wantCode: http.StatusOK, wantStatus: StatusUp},
+		{path: ReadinessPath, wantCode: http.StatusServiceUnavailable, wantStatus: StatusDown},
+		{path: "/v1/items", wantCode: http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.Mux(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantCode)
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("GET %s body: %v", tt.path, err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("GET %s status = %s, want %s", tt.path, report.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,7 +1,8 @@
 package logger
 
 import (
-	"github.com/ovargas/wizapp/sdk/app"
+	"fmt"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -16,65 +17,70 @@ type (
 	// Config is the configuration for the logger.
 	Config struct {
 		// The default logging level.
-		Level string `mapstructure:"level"`
+		Level string `mapstructure:"level" validate:"oneof=panic fatal error warn warning info debug trace"`
 		// The formatter to use (default: text, options: text, json, stackdriver).
-		Formatter string `mapstructure:"formatter"`
+		Formatter string `mapstructure:"formatter" validate:"oneof=text json stackdriver"`
 	}
 )
 
-// logger is a global variable that provides central logging capabilities.
-var logger Logger
-var level string
-
-// init initializes the global logger if AUTO_INIT is set to true or no logger instance is already present.
-// if no configuration is found the default configuration is used.
-func init() {
-
-	var cfg *Config
-	defaultLogger := false
-	_ = app.Config().UnmarshalKey("logger", &cfg)
-	if cfg == nil { // use logrus as default logger
-		defaultLogger = true
-		cfg = &Config{
-			Level: "info",
-		}
-	}
+// ConfigKey is the configuration key of the logger.
+const ConfigKey = "logger"
 
-	logger = setupLogger(cfg)
-	level = cfg.Level
-
-	if defaultLogger {
-		logger.Debug("No logger configuration found. Using default logger.")
-	}
-}
+// logger is a global variable that provides central logging capabilities.
+// It starts as a bootstrap logger at info level with the text formatter, and it is configured in place
+// once the application configuration is resolved, so the loggers taken with Log before that are configured as well.
+var logger = logrus.New()
+
+var (
+	configureMu sync.Mutex
+	// configuredLevel is the level of the last configuration applied, see Configure.
+	configuredLevel string
+)
 
-// setupLogger creates a new logrus.Logger with the given configuration
-func setupLogger(cfg *Config) Logger {
-	l := logrus.New()
-	if lvl, err := logrus.ParseLevel(cfg.Level); err == nil {
-		l.SetLevel(lvl)
+// Configure applies the configuration to the global logger.
+// An empty level or formatter keeps the default one, info and text respectively.
+// The level is only changed when it differs from the one of the previous configuration, so a configuration reload
+// keeps the level changed at runtime with SetLevel.
+func Configure(cfg *Config) error {
+	lvl := logrus.InfoLevel
+	if cfg.Level != "" {
+		l, err := logrus.ParseLevel(cfg.Level)
+		if err != nil {
+			return err
+		}
+		lvl = l
 	}
 
-	l.SetReportCaller(false)
-
+	var formatter logrus.Formatter
 	switch cfg.Formatter {
 	case "stackdriver":
-		l.Formatter = stackdriver()
+		formatter = stackdriver()
 	case "json":
-		l.Formatter = &logrus.JSONFormatter{}
+		formatter = &logrus.JSONFormatter{}
+	case "", "text":
+		formatter = &logrus.TextFormatter{}
 	default:
+		return fmt.Errorf("unknown logger formatter %s", cfg.Formatter)
 	}
 
-	return l
+	configureMu.Lock()
+	defer configureMu.Unlock()
+
+	logger.SetReportCaller(false)
+	logger.SetFormatter(formatter)
+	if cfg.Level != configuredLevel {
+		logger.SetLevel(lvl)
+		configuredLevel = cfg.Level
+	}
+	return nil
 }
 
 // ConfigureLogger is a convenience function to configure the logger.
 // Calling this function overrides any previous configuration.
 func ConfigureLogger(cfg *Config) {
-	if logger != nil {
-		logrus.Warn("Overriding previous logger configuration.")
+	if err := Configure(cfg); err != nil {
+		logger.Warnf("Invalid logger configuration: %v", err)
 	}
-	logger = setupLogger(cfg)
 }
 
 // Log is a getter for the logger.
@@ -86,11 +92,23 @@ func Log() Logger {
 	return logger
 }
 
+// SetLevel changes the logging level of the global logger.
+//
+// Values: panic, fatal, error, warn, info, debug, trace
+func SetLevel(lvl string) error {
+	l, err := logrus.ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	logger.SetLevel(l)
+	return nil
+}
+
 // Level the current logging level
 //
 // Values: panic, fatal, error, warn, info, debug, trace
 func Level() string {
-	return level
+	return logger.GetLevel().String()
 }
 
 // LoggingCategory represent a logging category.
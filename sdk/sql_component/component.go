@@ -1,6 +1,7 @@
 package sql_component
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -8,9 +9,13 @@ import (
 	"github.com/ovargas/wizapp/sdk/datasource"
 	"github.com/ovargas/wizapp/sdk/logger"
 	"github.com/urfave/cli/v2"
+	"sort"
+	"strings"
+	"unicode"
 )
 
 const (
+	ComponentName  = "sql"
 	FlagDatasource = "datasource"
 )
 
@@ -22,29 +27,48 @@ type (
 	Config struct {
 		datasource.Config `mapstructure:",squash"`
 		MigrationPath     string `mapstructure:"migration_path"`
+		// MigrateOnStart applies the migrations when the application starts, before the servers are started.
+		MigrateOnStart bool `mapstructure:"migrate_on_start"`
+	}
+
+	// MigrationModule is an app.Module declaring migration scripts by datasource name,
+	// e.g. file://internal/items/migrations. The scripts of the enabled modules are applied after the ones of the
+	// datasource migration_path, the versions of each module are recorded in its schema_migrations_<module> table.
+	MigrationModule interface {
+		app.Module
+		Migrations() map[string]string
 	}
 
 	component struct {
 		app.UnimplementedComponent
-		config map[string]Config
+		config *app.ApplicationConfig
+	}
+
+	// migration applies the scripts of the datasource, or of a module.
+	migration struct {
+		*migrate.Migrate
+		module string
 	}
 )
 
 func init() {
-	app.RegisterComponent("sql", createComponent)
+	app.RegisterComponent(ComponentName, createComponent)
+	app.RegisterConfig(datasource.ConfigKey, map[string]Config{})
+}
+
+// Install registers the sql component in the application.
+func Install(a *app.App) {
+	a.RegisterComponent(ComponentName, createComponent)
+	a.RegisterConfig(datasource.ConfigKey, map[string]Config{})
 }
 
 func createComponent(cfg *app.ApplicationConfig) (app.Component, error) {
-	var dsCfg map[string]Config
-	if err := cfg.UnmarshalKey("datasource", &dsCfg); err != nil {
-		return nil, err
-	}
-	return &component{config: dsCfg}, nil
+	return &component{config: cfg}, nil
 }
 
 func (c *component) Command() *app.Command {
 	return &app.Command{
-		Name:  "sql",
+		Name:  ComponentName,
 		Usage: "Sql database operations",
 		Subcommands: []*app.Command{
 			{
@@ -71,54 +95,153 @@ func (c *component) Command() *app.Command {
 	}
 }
 
-func (c *component) migrate(ctx *cli.Context) error {
-	name := ctx.String(FlagDatasource)
-	m, err := c.getMigration(ctx)
+// Init applies the migrations of the datasources configured with migrate_on_start.
+func (c *component) Init(config *app.ApplicationConfig) error {
+	dsCfg, err := app.BindFrom[map[string]Config](config, datasource.ConfigKey)
 	if err != nil {
 		return err
 	}
-	err = m.Up()
+
+	names := make([]string, 0, len(dsCfg))
+	for name, cfg := range dsCfg {
+		if cfg.MigrateOnStart {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.up(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *component) migrate(ctx *cli.Context) error {
+	return c.up(ctx.String(FlagDatasource))
+}
+
+// up applies the migrations of the datasource.
+func (c *component) up(name string) error {
+	migrations, err := c.getMigrations(name)
 	if err != nil {
-		log.Errorf("error applying datasource %s migration: %v", name, err)
 		return err
 	}
+	defer closeMigrations(migrations)
+
+	for _, m := range migrations {
+		err = m.Up()
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Infof("datasource %s%s migration: no change", name, m.module)
+			continue
+		}
+		if err != nil {
+			log.Errorf("error applying datasource %s%s migration: %v", name, m.module, err)
+			return err
+		}
+	}
 	return nil
 }
 
 func (c *component) version(ctx *cli.Context) error {
 	name := ctx.String(FlagDatasource)
 
-	m, err := c.getMigration(ctx)
-	if err != nil {
-		return err
-	}
-	version, dirty, err := m.Version()
+	migrations, err := c.getMigrations(name)
 	if err != nil {
-		log.Errorf("error fetching datasource %s schema version: %v", name, err)
 		return err
 	}
+	defer closeMigrations(migrations)
 
-	fmt.Printf("Datasource %s version %d, dirty %t\n", ctx.String(FlagDatasource), version, dirty)
+	for _, m := range migrations {
+		version, dirty, err := m.Version()
+		if err != nil {
+			log.Errorf("error fetching datasource %s%s schema version: %v", name, m.module, err)
+			return err
+		}
+
+		fmt.Printf("Datasource %s%s version %d, dirty %t\n", name, m.module, version, dirty)
+	}
 	return nil
 }
 
-func (c *component) getMigration(ctx *cli.Context) (*migrate.Migrate, error) {
-	name := ctx.String(FlagDatasource)
+// getMigrations returns the migration of the datasource migration_path, then the migrations of the enabled modules.
+func (c *component) getMigrations(name string) ([]migration, error) {
+	dsCfg, err := app.BindFrom[map[string]Config](c.config, datasource.ConfigKey)
+	if err != nil {
+		return nil, err
+	}
 
-	config, ok := c.config[name]
+	config, ok := dsCfg[name]
 
 	if !ok {
 		log.Errorf("datasource %s no configured", name)
 		return nil, datasource.ErrDataSourceNotConfigured
 	}
 
-	log.Infof("migrating with %v", config)
+	log.Infof("migrating with %v", app.Redact(config))
 
-	m, err := migrate.New(config.MigrationPath, fmt.Sprintf("%s://%s", config.DriverName, config.ConnectionString))
+	var modules []MigrationModule
+	for _, m := range app.ModulesOf[MigrationModule](c.config) {
+		if m.Migrations()[name] != "" {
+			modules = append(modules, m)
+		}
+	}
+
+	var migrations []migration
+	if config.MigrationPath != "" || len(modules) == 0 {
+		m, err := c.newMigration(name, config.MigrationPath, databaseURL(config, ""))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{Migrate: m})
+	}
+
+	for _, module := range modules {
+		m, err := c.newMigration(name, module.Migrations()[name], databaseURL(config, migrationsTable(module.Name())))
+		if err != nil {
+			closeMigrations(migrations)
+			return nil, err
+		}
+		migrations = append(migrations, migration{Migrate: m, module: " module " + module.Name()})
+	}
+	return migrations, nil
+}
+
+func (c *component) newMigration(name, sourceURL, databaseURL string) (*migrate.Migrate, error) {
+	m, err := migrate.New(sourceURL, databaseURL)
 	if err != nil {
-		log.Errorf("error creating migration %s: %v", name, err)
+		log.Errorf("error creating migration %s: %s", name, c.config.RedactString(err.Error()))
 		return nil, err
 	}
-
 	return m, nil
 }
+
+func closeMigrations(migrations []migration) {
+	for _, m := range migrations {
+		_, _ = m.Close()
+	}
+}
+
+// databaseURL returns the migrate URL of the datasource, recording the versions in the given table when set.
+func databaseURL(config Config, table string) string {
+	url := fmt.Sprintf("%s://%s", config.DriverName, config.ConnectionString)
+	if table == "" {
+		return url
+	}
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	return url + separator + "x-migrations-table=" + table
+}
+
+// migrationsTable returns the table recording the versions of the module migrations.
+func migrationsTable(module string) string {
+	return "schema_migrations_" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, module)
+}
@@ -2,7 +2,10 @@ package temporal_server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/ovargas/wizapp/sdk/app"
+	"github.com/ovargas/wizapp/sdk/health"
 	"github.com/ovargas/wizapp/sdk/logger"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/converter"
@@ -10,28 +13,29 @@ import (
 	temporal_log "go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
+	"reflect"
+	"sync"
 	"time"
 )
 
 const (
 	ServiceName = "temporal-worker"
+	ConfigKey   = "temporal"
+
+	HealthCheckName = "temporal"
+	// ClientResourceName is the name of the temporal client resource of the commands, see ClientFromCommand.
+	ClientResourceName = "temporal-client"
 )
 
 var (
-	log = logger.Log()
-
-	registry           func(w Worker)
-	onFatalError       func(error)
-	workerInterceptors []WorkerInterceptor
-	temporalLogger     Logger
-	metricHandler      MetricHandler
-	identity           string
-	dataConverter      DataConverter
-	contextPropagators []ContextPropagator
+	log             = logger.Log()
+	defaultRegistry = NewRegistry()
 )
 
 func init() {
-	app.RegisterServer(ServiceName, createWorker)
+	app.RegisterServer(ServiceName, defaultRegistry.CreateWorker)
+	app.RegisterResource(ClientResourceName, defaultRegistry.CreateClient)
+	app.RegisterConfig(ConfigKey, Config{})
 }
 
 type (
@@ -43,8 +47,19 @@ type (
 
 	server struct {
 		app.UnimplementedServer
+		registry  *Registry
+		config    Config
+		client    client.Client
 		worker    worker.Worker
+		onFatal   func(error)
+		mu        sync.Mutex
 		isStarted bool
+		// stopping is set by Stop, a worker stopped while it starts returns at once.
+		stopping  bool
+		ready     chan struct{}
+		stopped   chan struct{}
+		fatal     chan error
+		closeOnce sync.Once
 	}
 
 	WorkerConfig struct {
@@ -75,7 +90,7 @@ type (
 	}
 
 	Config struct {
-		HostPort  string       `mapstructure:"host_port"`
+		HostPort  string       `mapstructure:"host_port" validate:"hostport"`
 		Namespace string       `mapstructure:"namespace"`
 		TaskQueue string       `mapstructure:"task_queue"`
 		Worker    WorkerConfig `mapstructure:"worker"`
@@ -85,61 +100,201 @@ type (
 		worker.WorkflowRegistry
 		worker.ActivityRegistry
 	}
+
+	// WorkerModule is an app.Module declaring workflows and activities, they are registered in the worker when the
+	// module is enabled.
+	WorkerModule interface {
+		app.Module
+		RegisterWorker(w Worker)
+	}
+
+	// Registry holds the options, workflows and activities used to create the worker of an application.
+	Registry struct {
+		mu                 sync.Mutex
+		registry           func(w Worker)
+		onFatalError       func(error)
+		workerInterceptors []WorkerInterceptor
+		temporalLogger     Logger
+		metricHandler      MetricHandler
+		identity           string
+		dataConverter      DataConverter
+		contextPropagators []ContextPropagator
+		health             *health.Registry
+		healthOnce         sync.Once
+
+		// runningMu guards running, the client of the started worker checked by the health check.
+		runningMu sync.RWMutex
+		running   client.Client
+	}
 )
 
+// NewRegistry creates an empty Registry registering its checks in the default health registry.
+func NewRegistry() *Registry {
+	return &Registry{health: health.Default()}
+}
+
+// Install registers a temporal worker and the temporal client resource in the application and returns the Registry
+// used to create them. The temporal client health check is registered in the application health registry.
+func Install(a *app.App) *Registry {
+	r := &Registry{health: a.Health()}
+	a.RegisterServer(ServiceName, r.CreateWorker)
+	a.RegisterResource(ClientResourceName, r.CreateClient)
+	a.RegisterConfig(ConfigKey, Config{})
+	return r
+}
+
+// ClientFromCommand returns the temporal client of the command, it is created on first use and closed once the
+// command completes.
+func ClientFromCommand(ctx *app.CommandContext) (client.Client, error) {
+	return app.Resource[client.Client](ctx, ClientResourceName)
+}
+
 func SetLogger(logger Logger) {
-	temporalLogger = logger
+	defaultRegistry.SetLogger(logger)
 }
 
 func SetMetricHandler(handler MetricHandler) {
-	metricHandler = handler
+	defaultRegistry.SetMetricHandler(handler)
 }
 
 func SetIdentity(name string) {
-	identity = name
+	defaultRegistry.SetIdentity(name)
 }
 
 func SetDataConverter(converter DataConverter) {
-	dataConverter = converter
+	defaultRegistry.SetDataConverter(converter)
 }
 
 func SetContextPropagators(propagators ...ContextPropagator) {
-	contextPropagators = append(contextPropagators, propagators...)
+	defaultRegistry.SetContextPropagators(propagators...)
 }
 
 func WorkerRegistry(fn func(w Worker)) {
-	registry = fn
+	defaultRegistry.WorkerRegistry(fn)
 }
 
 func OnFatalError(fn func(error)) {
-	onFatalError = fn
+	defaultRegistry.OnFatalError(fn)
 }
 
 func WorkerInterceptors(interceptors ...WorkerInterceptor) {
-	workerInterceptors = append(interceptors, interceptors...)
+	defaultRegistry.WorkerInterceptors(interceptors...)
+}
+
+func (r *Registry) SetLogger(logger Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.temporalLogger = logger
+}
+
+func (r *Registry) SetMetricHandler(handler MetricHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metricHandler = handler
+}
+
+func (r *Registry) SetIdentity(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identity = name
+}
+
+func (r *Registry) SetDataConverter(converter DataConverter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dataConverter = converter
 }
 
-func createWorker(config *app.ApplicationConfig) (app.Server, error) {
-	var cfg Config
+func (r *Registry) SetContextPropagators(propagators ...ContextPropagator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contextPropagators = append(r.contextPropagators, propagators...)
+}
+
+func (r *Registry) WorkerRegistry(fn func(w Worker)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registry = fn
+}
+
+func (r *Registry) OnFatalError(fn func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFatalError = fn
+}
 
-	if err := config.UnmarshalKey("temporal", &cfg); err != nil {
+func (r *Registry) WorkerInterceptors(interceptors ...WorkerInterceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workerInterceptors = append(r.workerInterceptors, interceptors...)
+}
+
+// SetHealthRegistry sets the registry of the temporal client health check.
+func (r *Registry) SetHealthRegistry(registry *health.Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health = registry
+}
+
+// CreateClient is the app.ResourceFactory of the temporal client of the commands.
+func (r *Registry) CreateClient(config *app.ApplicationConfig) (interface{}, error) {
+	cfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
 		return nil, err
 	}
 
-	dial, err := client.Dial(client.Options{
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dial(cfg)
+}
+
+// dial connects a client with the options of the registry, r.mu must be held.
+func (r *Registry) dial(cfg Config) (client.Client, error) {
+	return client.Dial(client.Options{
 		HostPort:           cfg.HostPort,
 		Namespace:          cfg.Namespace,
-		Logger:             temporalLogger,
-		MetricsHandler:     metricHandler,
-		Identity:           identity,
-		DataConverter:      dataConverter,
-		ContextPropagators: contextPropagators,
+		Logger:             r.temporalLogger,
+		MetricsHandler:     r.metricHandler,
+		Identity:           r.identity,
+		DataConverter:      r.dataConverter,
+		ContextPropagators: r.contextPropagators,
 	})
+}
+
+// CreateWorker is the app.ServerFactory of the temporal worker.
+func (r *Registry) CreateWorker(config *app.ApplicationConfig) (app.Server, error) {
+	cfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
+	dial, err := r.dial(cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	// the check is registered once, the worker is created again when it is restarted.
+	r.healthOnce.Do(func() {
+		r.health.Register(health.Check{
+			Name:  HealthCheckName,
+			Kind:  health.Readiness,
+			Check: r.checkHealth,
+		})
+	})
+
+	srv := &server{
+		registry: r,
+		config:   cfg,
+		client:   dial,
+		onFatal:  r.onFatalError,
+		ready:    make(chan struct{}),
+		stopped:  make(chan struct{}),
+		fatal:    make(chan error, 1),
+	}
+
 	w := worker.New(dial, cfg.TaskQueue, worker.Options{
 		MaxConcurrentActivityExecutionSize:      cfg.Worker.MaxConcurrentActivityExecutionSize,
 		WorkerActivitiesPerSecond:               cfg.Worker.WorkerActivitiesPerSecond,
@@ -163,44 +318,138 @@ func createWorker(config *app.ApplicationConfig) (app.Server, error) {
 		DeadlockDetectionTimeout:                cfg.Worker.DeadlockDetectionTimeout,
 		MaxHeartbeatThrottleInterval:            cfg.Worker.MaxHeartbeatThrottleInterval,
 		DefaultHeartbeatThrottleInterval:        cfg.Worker.DefaultHeartbeatThrottleInterval,
-		Interceptors:                            workerInterceptors,
-		OnFatalError:                            onFatalError,
+		Interceptors:                            r.workerInterceptors,
+		OnFatalError:                            srv.onFatalError,
 		DisableEagerActivities:                  cfg.Worker.DisableEagerActivities,
 		MaxConcurrentEagerActivityExecutionSize: cfg.Worker.MaxConcurrentEagerActivityExecutionSize,
 		DisableRegistrationAliasing:             cfg.Worker.DisableRegistrationAliasing,
 	})
 
-	if registry != nil {
-		registry(w)
+	if r.registry != nil {
+		r.registry(w)
+	}
+	for _, m := range app.ModulesOf[WorkerModule](config) {
+		m.RegisterWorker(w)
+	}
+
+	srv.worker = w
+	return srv, nil
+}
+
+// checkHealth checks the client of the started worker.
+func (r *Registry) checkHealth(ctx context.Context) error {
+	r.runningMu.RLock()
+	c := r.running
+	r.runningMu.RUnlock()
+	if c == nil {
+		return errors.New("temporal worker not started")
+	}
+	_, err := c.CheckHealth(ctx, &client.CheckHealthRequest{})
+	return err
+}
+
+// setRunning sets the client checked by the health check, or clears it when c is not the checked client.
+func (r *Registry) setRunning(c client.Client, started bool) {
+	r.runningMu.Lock()
+	defer r.runningMu.Unlock()
+	if started {
+		r.running = c
+	} else if r.running == c {
+		r.running = nil
 	}
+}
+
+// onFatalError reports the worker fatal error to Start so the application supervisor is notified.
+func (w *server) onFatalError(err error) {
+	if w.onFatal != nil {
+		w.onFatal(err)
+	}
+	select {
+	case w.fatal <- err:
+	default:
+	}
+}
 
-	return &server{
-		worker: w,
-	}, nil
+// Reload requires a restart when the temporal configuration changed, the worker options are fixed once created.
+func (w *server) Reload(config *app.ApplicationConfig) error {
+	cfg, err := app.BindFrom[Config](config, ConfigKey)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(cfg, w.config) {
+		return fmt.Errorf("%w: %s configuration changed", app.ErrRestartRequired, ConfigKey)
+	}
+	return nil
 }
 
 func (w *server) Start() error {
-	if w.isStarted {
+	w.mu.Lock()
+	if w.isStarted || w.stopping {
+		w.mu.Unlock()
 		return nil
 	}
+	w.mu.Unlock()
 
+	if err := w.worker.Start(); err != nil {
+		return fmt.Errorf("unable to start temporal worker: %w", err)
+	}
+
+	w.mu.Lock()
+	if w.stopping {
+		w.mu.Unlock()
+		w.worker.Stop()
+		return nil
+	}
 	w.isStarted = true
+	w.mu.Unlock()
 
-	defer func() {
-		w.isStarted = false
-	}()
+	w.registry.setRunning(w.client, true)
+	close(w.ready)
 
-	if err := w.worker.Run(worker.InterruptCh()); err != nil {
-		log.Fatalf("Unable to start temporal worker: %v", err)
+	select {
+	case <-w.stopped:
+		return nil
+	case err := <-w.fatal:
+		w.mu.Lock()
+		w.isStarted = false
+		w.mu.Unlock()
+		return err
 	}
+}
 
-	return nil
+// Ready is closed once the worker is polling its task queue.
+func (w *server) Ready() <-chan struct{} {
+	return w.ready
 }
 
-func (w *server) Stop() error {
-	if w.isStarted {
+// Stop stops the worker, waiting for the running activities up to the worker_stop_timeout or the context deadline.
+// The client dialed by the factory is closed even if the worker never started.
+func (w *server) Stop(ctx context.Context) error {
+	defer w.closeOnce.Do(w.client.Close)
+	w.registry.setRunning(w.client, false)
+
+	w.mu.Lock()
+	started, stopping := w.isStarted, w.stopping
+	w.isStarted, w.stopping = false, true
+	w.mu.Unlock()
+	if !stopping {
+		defer close(w.stopped)
+	}
+	if !started {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
 		w.worker.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
 		log.Infof("temporal worker stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return nil
 }
@@ -0,0 +1,157 @@
+package temporal_server
+
+import (
+	"context"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient counts the calls of the client methods used by the worker server.
+type fakeClient struct {
+	client.Client
+	checks int
+	closed int
+}
+
+func (c *fakeClient) CheckHealth(context.Context, *client.CheckHealthRequest) (*client.CheckHealthResponse, error) {
+	c.checks++
+	return &client.CheckHealthResponse{}, nil
+}
+
+func (c *fakeClient) Close() {
+	c.closed++
+}
+
+func TestServer_Stop_closesTheClient(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops int
+	}{
+		{name: "not started", stops: 1},
+		{name: "stopped twice", stops: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeClient{}
+			srv := &server{registry: NewRegistry(), client: c, stopped: make(chan struct{})}
+			for i := 0; i < tt.stops; i++ {
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+			if c.closed != 1 {
+				t.Errorf("client closed %d times, want 1", c.closed)
+			}
+		})
+	}
+}
+
+// fakeWorker is a worker.Worker whose Start closes entered, then waits for starting to be closed.
+type fakeWorker struct {
+	worker.Worker
+	entered  chan struct{}
+	starting chan struct{}
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+}
+
+func (w *fakeWorker) Start() error {
+	close(w.entered)
+	<-w.starting
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.started = true
+	return nil
+}
+
+func (w *fakeWorker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+}
+
+func TestServer_Stop_beforeTheWorkerIsStarted(t *testing.T) {
+	tests := []struct {
+		name string
+		// during stops the server while the worker starts, instead of before Start.
+		during      bool
+		wantStarted bool
+	}{
+		{name: "stopped before Start"},
+		{name: "stopped while the worker starts", during: true, wantStarted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeWorker{entered: make(chan struct{}), starting: make(chan struct{})}
+			srv := &server{
+				registry: NewRegistry(),
+				client:   &fakeClient{},
+				worker:   w,
+				ready:    make(chan struct{}),
+				stopped:  make(chan struct{}),
+				fatal:    make(chan error, 1),
+			}
+			if !tt.during {
+				close(w.starting)
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.Start()
+			}()
+			if tt.during {
+				<-w.entered
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+				close(w.starting)
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Start() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start() still running once stopped")
+			}
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if w.started != tt.wantStarted || w.stopped != tt.wantStarted {
+				t.Errorf("worker started = %t, stopped = %t, want %t", w.started, w.stopped, tt.wantStarted)
+			}
+		})
+	}
+}
+
+func TestRegistry_checkHealth(t *testing.T) {
+	r := NewRegistry()
+	previous, current := &fakeClient{}, &fakeClient{}
+
+	if err := r.checkHealth(context.Background()); err == nil {
+		t.Error("checkHealth() without started worker error = nil")
+	}
+
+	r.setRunning(previous, true)
+	r.setRunning(current, true)
+	// a previous instance stopped after the restarted one started keeps the check on the current client.
+	r.setRunning(previous, false)
+	if err := r.checkHealth(context.Background()); err != nil {
+		t.Fatalf("checkHealth() error = %v", err)
+	}
+	if previous.checks != 0 || current.checks != 1 {
+		t.Errorf("checks previous = %d, current = %d, want 0 and 1", previous.checks, current.checks)
+	}
+
+	r.setRunning(current, false)
+	if err := r.checkHealth(context.Background()); err == nil {
+		t.Error("checkHealth() once stopped error = nil")
+	}
+}